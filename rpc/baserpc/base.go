@@ -24,23 +24,34 @@ package baserpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
-	grpc_retry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 type BaseRpc struct {
-	Timeout    time.Duration
-	RetryTimes uint32
+	// timeout of each attempt
+	Timeout time.Duration
+	// max attempts of an rpc, at least once
+	RetryTimes  uint32
+	RetryPolicy RetryPolicy
+	// connections are shared through Pool, DefaultConnPool() is used if nil
+	Pool *ConnPool
+
+	mu     sync.RWMutex
+	leader string
 }
 
 type RpcContext struct {
-	addrs []string // endpoint: 127.0.0.1:6666
-	name  string
+	addrs      []string // endpoint: 127.0.0.1:6666
+	name       string
+	idempotent bool
 }
 
 type RpcResult struct {
@@ -56,71 +67,223 @@ type Rpc interface {
 
 func NewRpcContext(addrs []string, funcName string) *RpcContext {
 	return &RpcContext{
-		addrs: addrs,
-		name:  funcName,
+		addrs:      addrs,
+		name:       funcName,
+		idempotent: true,
+	}
+}
+
+// rpcs which change state and must not be resent blindly, such as CreateFile
+func NewNonIdempotentRpcContext(addrs []string, funcName string) *RpcContext {
+	return &RpcContext{
+		addrs:      addrs,
+		name:       funcName,
+		idempotent: false,
 	}
 }
 
+func (ctx *RpcContext) Name() string {
+	return ctx.name
+}
+
+func (cli *BaseRpc) connPool() *ConnPool {
+	if cli.Pool != nil {
+		return cli.Pool
+	}
+	return DefaultConnPool()
+}
+
 func (cli *BaseRpc) getOrCreateConn(addr string, ctx context.Context) (*grpc.ClientConn, error) {
-	// TODO: conn pool maybe needed
-	ctx, cancel := context.WithTimeout(context.Background(), cli.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, cli.Timeout)
 	defer cancel()
-	conn, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
-	if err != nil {
-		return nil, err
+	return cli.connPool().Get(ctx, addr)
+}
+
+// Leader returns the address which served the last successful rpc, empty if
+// no leader is known yet.
+func (cli *BaseRpc) Leader() string {
+	cli.mu.RLock()
+	defer cli.mu.RUnlock()
+	return cli.leader
+}
+
+func (cli *BaseRpc) setLeader(addr string) {
+	cli.mu.Lock()
+	cli.leader = addr
+	cli.mu.Unlock()
+}
+
+func (cli *BaseRpc) resetLeader(addr string) {
+	cli.mu.Lock()
+	if cli.leader == addr {
+		cli.leader = ""
 	}
-	return conn, nil
+	cli.mu.Unlock()
 }
 
-func (cli *BaseRpc) SendRpc(ctx *RpcContext, rpcFunc Rpc) *RpcResult {
-	size := len(ctx.addrs)
-	if size == 0 {
-		return &RpcResult{
-			Key:    "",
-			Err:    fmt.Errorf("empty addr"),
-			Result: nil,
-		}
+// only the leader mds serves rpc, the followers refuse connections, so
+// transport errors mean the addr is not (or no longer) the leader
+func isNotLeaderErr(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
 	}
-	results := make(chan RpcResult, size)
-	for _, addr := range ctx.addrs {
+}
+
+// discoverLeader dials all addrs concurrently and returns the first reachable
+// one, it stops waiting for the others once the leader is found while their
+// dials go on in the pool.
+func (cli *BaseRpc) discoverLeader(ctx context.Context, addrs []string) (string, []rpcerr.AddrError) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan RpcResult, len(addrs))
+	for _, addr := range addrs {
 		go func(address string) {
-			ctx, cancel := context.WithTimeout(context.Background(), cli.Timeout)
-			defer cancel()
-			conn, err := cli.getOrCreateConn(address, ctx)
-			if err != nil {
-				results <- RpcResult{
-					Key:    address,
-					Err:    err,
-					Result: nil,
-				}
-			} else {
-				rpcFunc.NewRpcClient(conn)
-				res, err := rpcFunc.Stub_Func(ctx, grpc_retry.WithMax(uint(cli.RetryTimes)),
-					grpc_retry.WithCodes(codes.Unknown, codes.Unavailable, codes.DeadlineExceeded))
-				results <- RpcResult{
-					Key:    address,
-					Err:    err,
-					Result: res,
-				}
-				conn.Close()
+			_, err := cli.getOrCreateConn(address, ctx)
+			results <- RpcResult{
+				Key: address,
+				Err: err,
 			}
 		}(addr)
 	}
-	count := 0
-	var rpcErr string
-	for res := range results {
+	var errs []rpcerr.AddrError
+	for i := 0; i < len(addrs); i++ {
+		res := <-results
 		if res.Err == nil {
-			return &res
+			return res.Key.(string), nil
 		}
-		count++
-		rpcErr = fmt.Sprintf("%s;%s:%s", rpcErr, res.Key, res.Err.Error())
-		if count >= size {
-			break
+		errs = append(errs, rpcerr.AddrError{Addr: res.Key.(string), Err: res.Err})
+	}
+	return "", errs
+}
+
+// sendRpcTo returns whether the request has been sent besides the result
+func (cli *BaseRpc) sendRpcTo(ctx context.Context, addr string, rpcFunc Rpc) (*RpcResult, bool) {
+	conn, err := cli.getOrCreateConn(addr, ctx)
+	if err != nil {
+		return &RpcResult{
+			Key:    addr,
+			Err:    err,
+			Result: nil,
+		}, false
+	}
+	ctx, cancel := context.WithTimeout(ctx, cli.Timeout)
+	defer cancel()
+	rpcFunc.NewRpcClient(conn)
+	res, err := rpcFunc.Stub_Func(ctx)
+	return &RpcResult{
+		Key:    addr,
+		Err:    err,
+		Result: res,
+	}, true
+}
+
+// sendRpcToLeader sends the rpc to the leader and fails over to the other
+// addrs on transport errors, it returns whether the rpc can be retried. a
+// non-idempotent rpc fails over only if it was never sent, since a timeout
+// does not tell whether the leader has applied it.
+func (cli *BaseRpc) sendRpcToLeader(ctx context.Context, rpcCtx *RpcContext, rpcFunc Rpc) (*RpcResult, bool) {
+	policy := &cli.RetryPolicy
+	canResend := rpcCtx.idempotent || policy.RetryNonIdempotent
+
+	leader := cli.Leader()
+	if !contains(rpcCtx.addrs, leader) {
+		var errs []rpcerr.AddrError
+		leader, errs = cli.discoverLeader(ctx, rpcCtx.addrs)
+		if leader == "" {
+			return &RpcResult{
+				Key:    "",
+				Err:    &rpcerr.TransportError{Rpc: rpcCtx.name, Errs: errs},
+				Result: nil,
+			}, ctx.Err() == nil
+		}
+	}
+	candidates := []string{leader}
+	for _, addr := range rpcCtx.addrs {
+		if addr != leader {
+			candidates = append(candidates, addr)
+		}
+	}
+
+	var errs []rpcerr.AddrError
+	sent := false
+	for _, addr := range candidates {
+		res, ok := cli.sendRpcTo(ctx, addr, rpcFunc)
+		sent = sent || ok
+		if res.Err == nil {
+			cli.setLeader(addr)
+			return res, canResend && policy.isRetryableStatus(res.Result)
+		}
+		errs = append(errs, rpcerr.AddrError{Addr: addr, Err: res.Err})
+		res.Err = &rpcerr.TransportError{Rpc: rpcCtx.name, Errs: errs}
+		if ctx.Err() != nil {
+			return res, false
+		}
+		if !isNotLeaderErr(errs[len(errs)-1].Err) {
+			cli.setLeader(addr)
+			return res, canResend && policy.isRetryableCode(errs[len(errs)-1].Err)
+		}
+		cli.resetLeader(addr)
+		if ok && !canResend {
+			return res, false
 		}
 	}
 	return &RpcResult{
 		Key:    "",
-		Err:    fmt.Errorf(rpcErr),
+		Err:    &rpcerr.TransportError{Rpc: rpcCtx.name, Errs: errs},
 		Result: nil,
+	}, canResend || !sent
+}
+
+func (cli *BaseRpc) SendRpc(ctx *RpcContext, rpcFunc Rpc) *RpcResult {
+	return cli.SendRpcWithContext(context.Background(), ctx, rpcFunc)
+}
+
+// SendRpcWithContext sends the rpc to the leader of addrs only. The leader is
+// found by dialing all addrs at the first time and then sticked to, the other
+// addrs are tried in order only if the leader fails with transport errors.
+// Failed rpcs are retried with backoff according to RetryPolicy, all attempts
+// stop once ctx is done.
+func (cli *BaseRpc) SendRpcWithContext(ctx context.Context, rpcCtx *RpcContext, rpcFunc Rpc) *RpcResult {
+	size := len(rpcCtx.addrs)
+	if size == 0 {
+		return &RpcResult{
+			Key:    "",
+			Err:    fmt.Errorf("%s: empty addr", rpcCtx.name),
+			Result: nil,
+		}
+	}
+
+	if cli.RetryPolicy.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cli.RetryPolicy.Deadline)
+		defer cancel()
+	}
+	for retry := 0; ; retry++ {
+		res, retryable := cli.sendRpcToLeader(ctx, rpcCtx, rpcFunc)
+		if !retryable || retry+1 >= int(cli.RetryTimes) {
+			return res
+		}
+		timer := time.NewTimer(cli.RetryPolicy.backoff(retry))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res
+		case <-timer.C:
+		}
+	}
+}
+
+func contains(addrs []string, addr string) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
 	}
+	return false
 }
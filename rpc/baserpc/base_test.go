@@ -0,0 +1,240 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package baserpc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type healthCheckRpc struct {
+	client   grpc_health_v1.HealthClient
+	attempts int
+}
+
+func (rpc *healthCheckRpc) NewRpcClient(cc grpc.ClientConnInterface) {
+	rpc.client = grpc_health_v1.NewHealthClient(cc)
+}
+
+func (rpc *healthCheckRpc) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
+	rpc.attempts++
+	return rpc.client.Check(ctx, &grpc_health_v1.HealthCheckRequest{}, opt...)
+}
+
+func startHealthServer(t *testing.T) (string, *grpc.Server) {
+	return startServer(t, func(gs *grpc.Server) {
+		grpc_health_v1.RegisterHealthServer(gs, health.NewServer())
+	})
+}
+
+func TestSendRpcToLeader(t *testing.T) {
+	leader, gs := startHealthServer(t)
+	defer gs.Stop()
+	pool := NewConnPool(ConnPoolOption{})
+	defer pool.Close()
+	cli := &BaseRpc{
+		Timeout: 500 * time.Millisecond,
+		Pool:    pool,
+	}
+
+	ctx := NewRpcContext([]string{"127.0.0.1:1", leader}, "Check")
+	for i := 0; i < 3; i++ {
+		ret := cli.SendRpc(ctx, &healthCheckRpc{})
+		if ret.Err != nil {
+			t.Fatalf("TestSendRpcToLeader rpc failed, error = %v", ret.Err)
+		}
+		if ret.Key != leader || cli.Leader() != leader {
+			t.Errorf("TestSendRpcToLeader expected leader = %s; actual key = %v, leader = %s",
+				leader, ret.Key, cli.Leader())
+		}
+	}
+
+	gs.Stop()
+	ret := cli.SendRpc(ctx, &healthCheckRpc{})
+	if ret.Err == nil {
+		t.Errorf("TestSendRpcToLeader expected error after leader stopped")
+	}
+	if cli.Leader() != "" {
+		t.Errorf("TestSendRpcToLeader expected leader reset, actual leader = %s", cli.Leader())
+	}
+}
+
+func TestSendRpcRetryStatus(t *testing.T) {
+	addr, gs := startHealthServer(t)
+	defer gs.Stop()
+	pool := NewConnPool(ConnPoolOption{})
+	defer pool.Close()
+	cli := &BaseRpc{
+		Timeout:    500 * time.Millisecond,
+		RetryTimes: 3,
+		RetryPolicy: RetryPolicy{
+			Backoff: time.Millisecond,
+			RetryableStatus: func(response interface{}) bool {
+				return true
+			},
+		},
+		Pool: pool,
+	}
+
+	rpc := &healthCheckRpc{}
+	ret := cli.SendRpc(NewRpcContext([]string{addr}, "Check"), rpc)
+	if ret.Err != nil {
+		t.Fatalf("TestSendRpcRetryStatus rpc failed, error = %v", ret.Err)
+	}
+	if rpc.attempts != 3 {
+		t.Errorf("TestSendRpcRetryStatus expected attempts = 3, actual attempts = %d", rpc.attempts)
+	}
+
+	rpc = &healthCheckRpc{}
+	ret = cli.SendRpc(NewNonIdempotentRpcContext([]string{addr}, "Check"), rpc)
+	if ret.Err != nil {
+		t.Fatalf("TestSendRpcRetryStatus rpc failed, error = %v", ret.Err)
+	}
+	if rpc.attempts != 1 {
+		t.Errorf("TestSendRpcRetryStatus expected no retry for non-idempotent rpc, actual attempts = %d", rpc.attempts)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		Backoff:    10 * time.Millisecond,
+		MaxBackoff: 40 * time.Millisecond,
+		Jitter:     0.5,
+	}
+	for retry, expected := range []time.Duration{10, 20, 40, 40} {
+		expected *= time.Millisecond
+		backoff := policy.backoff(retry)
+		if backoff < expected/2 || backoff > expected*3/2 {
+			t.Errorf("TestRetryBackoff retry = %d, expected backoff around %v, actual = %v", retry, expected, backoff)
+		}
+	}
+}
+
+func TestSendRpcWithCanceledContext(t *testing.T) {
+	addr, gs := startHealthServer(t)
+	defer gs.Stop()
+	pool := NewConnPool(ConnPoolOption{})
+	defer pool.Close()
+	cli := &BaseRpc{
+		Timeout:    500 * time.Millisecond,
+		RetryTimes: 3,
+		Pool:       pool,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rpc := &healthCheckRpc{}
+	ret := cli.SendRpcWithContext(ctx, NewRpcContext([]string{addr}, "Check"), rpc)
+	if ret.Err == nil {
+		t.Errorf("TestSendRpcWithCanceledContext expected error with canceled context")
+	}
+	if rpc.attempts != 0 {
+		t.Errorf("TestSendRpcWithCanceledContext expected no attempts, actual attempts = %d", rpc.attempts)
+	}
+}
+
+func TestCall(t *testing.T) {
+	addr, gs := startHealthServer(t)
+	defer gs.Stop()
+	pool := NewConnPool(ConnPoolOption{})
+	defer pool.Close()
+	cli := &BaseRpc{
+		Timeout: 500 * time.Millisecond,
+		Pool:    pool,
+	}
+	newStub := func(cc grpc.ClientConnInterface) UnaryFunc[*grpc_health_v1.HealthCheckRequest,
+		*grpc_health_v1.HealthCheckResponse] {
+		return grpc_health_v1.NewHealthClient(cc).Check
+	}
+
+	response, err := Call(context.Background(), cli, NewRpcContext([]string{addr}, "Check"), newStub,
+		&grpc_health_v1.HealthCheckRequest{}, nil)
+	if err != nil {
+		t.Fatalf("TestCall rpc failed, error = %v", err)
+	}
+	if response.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Errorf("TestCall expected SERVING, actual status = %v", response.GetStatus())
+	}
+
+	errNotServing := errors.New("not serving")
+	_, err = Call(context.Background(), cli, NewRpcContext([]string{addr}, "Check"), newStub,
+		&grpc_health_v1.HealthCheckRequest{},
+		func(name string, response *grpc_health_v1.HealthCheckResponse) error {
+			return errNotServing
+		})
+	if !errors.Is(err, errNotServing) {
+		t.Errorf("TestCall expected error of status checker, actual error = %v", err)
+	}
+}
+
+type hangingHealthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	checks *int32
+}
+
+func (s *hangingHealthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (
+	*grpc_health_v1.HealthCheckResponse, error) {
+	atomic.AddInt32(s.checks, 1)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestSendRpcNoFailoverAfterSent(t *testing.T) {
+	var checks int32
+	startHangingServer := func() (string, *grpc.Server) {
+		return startServer(t, func(gs *grpc.Server) {
+			grpc_health_v1.RegisterHealthServer(gs, &hangingHealthServer{checks: &checks})
+		})
+	}
+	leader, gs := startHangingServer()
+	defer gs.Stop()
+	follower, followerGs := startHangingServer()
+	defer followerGs.Stop()
+	pool := NewConnPool(ConnPoolOption{})
+	defer pool.Close()
+	cli := &BaseRpc{
+		Timeout:    100 * time.Millisecond,
+		RetryTimes: 3,
+		RetryPolicy: RetryPolicy{
+			Backoff: time.Millisecond,
+		},
+		Pool: pool,
+	}
+
+	rpc := &healthCheckRpc{}
+	ret := cli.SendRpc(NewNonIdempotentRpcContext([]string{leader, follower}, "Check"), rpc)
+	if ret.Err == nil {
+		t.Fatalf("TestSendRpcNoFailoverAfterSent expected error of hanging leader")
+	}
+	if rpc.attempts != 1 || atomic.LoadInt32(&checks) != 1 {
+		t.Errorf("TestSendRpcNoFailoverAfterSent expected attempts = 1, actual attempts = %d, checks = %d",
+			rpc.attempts, atomic.LoadInt32(&checks))
+	}
+}
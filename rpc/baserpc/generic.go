@@ -0,0 +1,79 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package baserpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// UnaryFunc is the signature of the methods of generated grpc clients.
+type UnaryFunc[Req, Resp any] func(ctx context.Context, in Req, opts ...grpc.CallOption) (Resp, error)
+
+// NewStub binds a method of generated grpc client to the connection, e.g.
+//
+//	func(cc grpc.ClientConnInterface) UnaryFunc[*Req, *Resp] {
+//		return topology.NewTopologyServiceClient(cc).ListPhysicalPool
+//	}
+type NewStub[Req, Resp any] func(cc grpc.ClientConnInterface) UnaryFunc[Req, Resp]
+
+// StatusChecker returns the error carried by the status code of response, nil if ok.
+type StatusChecker[Resp any] func(name string, response Resp) error
+
+// typedRpc adapts a typed stub to Rpc
+type typedRpc[Req, Resp any] struct {
+	newStub NewStub[Req, Resp]
+	stub    UnaryFunc[Req, Resp]
+	request Req
+}
+
+func (rpc *typedRpc[Req, Resp]) NewRpcClient(cc grpc.ClientConnInterface) {
+	rpc.stub = rpc.newStub(cc)
+}
+
+func (rpc *typedRpc[Req, Resp]) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
+	return rpc.stub(ctx, rpc.request, opt...)
+}
+
+// Call sends request through SendRpcWithContext and returns the typed response,
+// whose status code is checked by check if it is not nil.
+func Call[Req, Resp any](ctx context.Context, cli *BaseRpc, rpcCtx *RpcContext, newStub NewStub[Req, Resp],
+	request Req, check StatusChecker[Resp]) (Resp, error) {
+	var response Resp
+	rpc := &typedRpc[Req, Resp]{
+		newStub: newStub,
+		request: request,
+	}
+	ret := cli.SendRpcWithContext(ctx, rpcCtx, rpc)
+	if ret.Err != nil {
+		return response, ret.Err
+	}
+	response = ret.Result.(Resp)
+	if check != nil {
+		if err := check(rpcCtx.name, response); err != nil {
+			return response, err
+		}
+	}
+	return response, nil
+}
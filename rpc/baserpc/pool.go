@@ -0,0 +1,250 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package baserpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	DEFAULT_CONN_IDLE_TIMEOUT = 5 * time.Minute
+	DEFAULT_CONN_DIAL_TIMEOUT = 3 * time.Second
+)
+
+var (
+	defaultConnPool     *ConnPool
+	defaultConnPoolOnce sync.Once
+)
+
+type ConnPoolOption struct {
+	// connections not used for IdleTimeout are closed, 0 means DEFAULT_CONN_IDLE_TIMEOUT
+	IdleTimeout time.Duration
+	// a dial is given up after DialTimeout, 0 means DEFAULT_CONN_DIAL_TIMEOUT
+	DialTimeout time.Duration
+}
+
+type pooledConn struct {
+	addr     string
+	conn     *grpc.ClientConn
+	err      error
+	ready    chan struct{}
+	lastUsed int64 // unix nano
+}
+
+func (pc *pooledConn) touch() {
+	atomic.StoreInt64(&pc.lastUsed, time.Now().UnixNano())
+}
+
+func (pc *pooledConn) idleSince() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&pc.lastUsed))
+}
+
+func (pc *pooledConn) isReady() bool {
+	select {
+	case <-pc.ready:
+		return pc.err == nil
+	default:
+		return false
+	}
+}
+
+// ConnPool shares grpc connections between rpcs by address, it is safe for
+// concurrent use. A connection is dropped from the pool when it is idle for
+// too long or its connectivity state turns into TransientFailure/Shutdown,
+// and will be redialed on the next Get.
+type ConnPool struct {
+	mu     sync.Mutex
+	conns  map[string]*pooledConn
+	option ConnPoolOption
+	closed bool
+	done   chan struct{}
+	// dials are shared by callers, so they run on the pool's ctx rather than any caller's
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewConnPool(option ConnPoolOption) *ConnPool {
+	if option.IdleTimeout <= 0 {
+		option.IdleTimeout = DEFAULT_CONN_IDLE_TIMEOUT
+	}
+	if option.DialTimeout <= 0 {
+		option.DialTimeout = DEFAULT_CONN_DIAL_TIMEOUT
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := &ConnPool{
+		conns:  make(map[string]*pooledConn),
+		option: option,
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	go pool.evictLoop()
+	return pool
+}
+
+// the pool used by BaseRpc which is not given one
+func DefaultConnPool() *ConnPool {
+	defaultConnPoolOnce.Do(func() {
+		defaultConnPool = NewConnPool(ConnPoolOption{})
+	})
+	return defaultConnPool
+}
+
+// Get returns the pooled connection of addr, the connection is dialed in the
+// background if it is not in pool yet. ctx only bounds the wait of this caller,
+// giving up doesn't abort the dial shared with others.
+// Callers must not close the returned connection.
+func (p *ConnPool) Get(ctx context.Context, addr string) (*grpc.ClientConn, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("conn pool is closed")
+	}
+	pc, ok := p.conns[addr]
+	if !ok {
+		pc = &pooledConn{
+			addr:  addr,
+			ready: make(chan struct{}),
+		}
+		p.conns[addr] = pc
+		go p.dial(pc)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-pc.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if pc.err != nil {
+		return nil, pc.err
+	}
+	pc.touch()
+	return pc.conn, nil
+}
+
+func (p *ConnPool) dial(pc *pooledConn) {
+	ctx, cancel := context.WithTimeout(p.ctx, p.option.DialTimeout)
+	defer cancel()
+	pc.conn, pc.err = grpc.DialContext(ctx, pc.addr, grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithBlock())
+	pc.touch()
+	close(pc.ready)
+	if pc.err != nil {
+		p.remove(pc)
+		return
+	}
+
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		pc.conn.Close()
+		return
+	}
+	go p.watch(pc)
+}
+
+// watch drops the connection once it breaks, so the next Get redials
+// instead of waiting for grpc's reconnect backoff
+func (p *ConnPool) watch(pc *pooledConn) {
+	for {
+		state := pc.conn.GetState()
+		if state == connectivity.TransientFailure || state == connectivity.Shutdown {
+			p.remove(pc)
+			return
+		}
+		if !pc.conn.WaitForStateChange(context.Background(), state) {
+			return
+		}
+	}
+}
+
+func (p *ConnPool) remove(pc *pooledConn) {
+	p.mu.Lock()
+	if cur, ok := p.conns[pc.addr]; ok && cur == pc {
+		delete(p.conns, pc.addr)
+	}
+	p.mu.Unlock()
+	if pc.conn != nil {
+		pc.conn.Close()
+	}
+}
+
+func (p *ConnPool) evictLoop() {
+	ticker := time.NewTicker(p.option.IdleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.evictIdle()
+		}
+	}
+}
+
+func (p *ConnPool) evictIdle() {
+	var idles []*pooledConn
+	deadline := time.Now().Add(-p.option.IdleTimeout)
+	p.mu.Lock()
+	for addr, pc := range p.conns {
+		if pc.isReady() && pc.idleSince().Before(deadline) {
+			delete(p.conns, addr)
+			idles = append(idles, pc)
+		}
+	}
+	p.mu.Unlock()
+	for _, pc := range idles {
+		pc.conn.Close()
+	}
+}
+
+// Close closes all pooled connections, the pool can not be used anymore.
+func (p *ConnPool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	conns := p.conns
+	p.conns = make(map[string]*pooledConn)
+	p.mu.Unlock()
+
+	close(p.done)
+	p.cancel()
+	for _, pc := range conns {
+		if pc.isReady() {
+			pc.conn.Close()
+		}
+	}
+}
@@ -0,0 +1,146 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package baserpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+func startServer(t *testing.T, register func(gs *grpc.Server)) (string, *grpc.Server) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	gs := grpc.NewServer()
+	if register != nil {
+		register(gs)
+	}
+	go gs.Serve(lis)
+	return lis.Addr().String(), gs
+}
+
+func TestConnPoolReuse(t *testing.T) {
+	addr, gs := startServer(t, nil)
+	defer gs.Stop()
+	pool := NewConnPool(ConnPoolOption{})
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn1, err := pool.Get(ctx, addr)
+	if err != nil {
+		t.Fatalf("TestConnPoolReuse get conn failed, error = %v", err)
+	}
+	conn2, err := pool.Get(ctx, addr)
+	if err != nil {
+		t.Fatalf("TestConnPoolReuse get conn failed, error = %v", err)
+	}
+	if conn1 != conn2 {
+		t.Errorf("TestConnPoolReuse expected the same conn for %s", addr)
+	}
+
+	pool.Close()
+	if state := conn1.GetState(); state != connectivity.Shutdown {
+		t.Errorf("TestConnPoolReuse expected conn shutdown after close, actual state = %v", state)
+	}
+	if _, err := pool.Get(ctx, addr); err == nil {
+		t.Errorf("TestConnPoolReuse expected error from closed pool")
+	}
+}
+
+func TestConnPoolEvictIdle(t *testing.T) {
+	addr, gs := startServer(t, nil)
+	defer gs.Stop()
+	pool := NewConnPool(ConnPoolOption{IdleTimeout: 100 * time.Millisecond})
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn1, err := pool.Get(ctx, addr)
+	if err != nil {
+		t.Fatalf("TestConnPoolEvictIdle get conn failed, error = %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	conn2, err := pool.Get(ctx, addr)
+	if err != nil {
+		t.Fatalf("TestConnPoolEvictIdle get conn failed, error = %v", err)
+	}
+	if conn1 == conn2 {
+		t.Errorf("TestConnPoolEvictIdle expected idle conn to be evicted")
+	}
+}
+
+func TestConnPoolDialFail(t *testing.T) {
+	pool := NewConnPool(ConnPoolOption{DialTimeout: 100 * time.Millisecond})
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := pool.Get(ctx, "127.0.0.1:1"); err == nil {
+		t.Errorf("TestConnPoolDialFail expected dial error")
+	}
+	// the failed conn is removed once its dial returns
+	time.Sleep(50 * time.Millisecond)
+	pool.mu.Lock()
+	size := len(pool.conns)
+	pool.mu.Unlock()
+	if size != 0 {
+		t.Errorf("TestConnPoolDialFail expected failed conn removed, actual size = %d", size)
+	}
+}
+
+func TestConnPoolCanceledCaller(t *testing.T) {
+	addr, gs := startServer(t, nil)
+	defer gs.Stop()
+	pool := NewConnPool(ConnPoolOption{})
+	defer pool.Close()
+
+	// the first caller gives up, but the dial it started goes on for others
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pool.Get(canceled, addr); err != context.Canceled {
+		t.Errorf("TestConnPoolCanceledCaller expected context.Canceled, actual error = %v", err)
+	}
+	pool.mu.Lock()
+	pc, ok := pool.conns[addr]
+	pool.mu.Unlock()
+	if !ok {
+		t.Fatalf("TestConnPoolCanceledCaller expected the dial of %s kept in pool", addr)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := pool.Get(ctx, addr)
+	if err != nil {
+		t.Fatalf("TestConnPoolCanceledCaller get conn failed, error = %v", err)
+	}
+	if conn != pc.conn {
+		t.Errorf("TestConnPoolCanceledCaller expected the conn dialed for the canceled caller")
+	}
+}
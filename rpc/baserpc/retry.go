@@ -0,0 +1,102 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package baserpc
+
+import (
+	"math/rand"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DEFAULT_RETRY_BACKOFF     = 100 * time.Millisecond
+	DEFAULT_RETRY_MAX_BACKOFF = 2 * time.Second
+	DEFAULT_RETRY_JITTER      = 0.2
+)
+
+var (
+	DEFAULT_RETRY_CODES = []codes.Code{codes.Unknown, codes.Unavailable, codes.DeadlineExceeded}
+)
+
+// RetryPolicy decides whether and when a failed rpc is sent again, the max
+// attempts is BaseRpc.RetryTimes and each attempt is bounded by BaseRpc.Timeout.
+type RetryPolicy struct {
+	// backoff before the first retry, doubled for each following retry
+	Backoff time.Duration
+	// upper bound of backoff
+	MaxBackoff time.Duration
+	// backoff is randomized in [1-Jitter, 1+Jitter] times, 0 means DEFAULT_RETRY_JITTER
+	Jitter float64
+	// overall deadline of all attempts, 0 means bounded by attempts only
+	Deadline time.Duration
+	// grpc codes to retry, DEFAULT_RETRY_CODES is used if empty
+	Codes []codes.Code
+	// reports whether the response carries a retryable business status code
+	RetryableStatus func(response interface{}) bool
+	// rpcs created by NewNonIdempotentRpcContext are retried only if they were
+	// never sent, unless RetryNonIdempotent is set
+	RetryNonIdempotent bool
+}
+
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	backoff := p.Backoff
+	if backoff <= 0 {
+		backoff = DEFAULT_RETRY_BACKOFF
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DEFAULT_RETRY_MAX_BACKOFF
+	}
+	jitter := p.Jitter
+	if jitter <= 0 || jitter > 1 {
+		jitter = DEFAULT_RETRY_JITTER
+	}
+
+	for i := 0; i < retry && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return time.Duration(float64(backoff) * (1 + jitter*(2*rand.Float64()-1)))
+}
+
+func (p *RetryPolicy) isRetryableCode(err error) bool {
+	retryCodes := p.Codes
+	if len(retryCodes) == 0 {
+		retryCodes = DEFAULT_RETRY_CODES
+	}
+	code := status.Code(err)
+	for _, c := range retryCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) isRetryableStatus(response interface{}) bool {
+	return p.RetryableStatus != nil && p.RetryableStatus(response)
+}
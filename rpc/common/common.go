@@ -22,7 +22,42 @@
 
 package common
 
+import (
+	"fmt"
+)
+
 const (
-	GiB         = 1024 * 1024 * 1024
+	KiB         = 1024
+	MiB         = 1024 * KiB
+	GiB         = 1024 * MiB
+	TiB         = 1024 * GiB
+	PiB         = 1024 * TiB
 	TIME_FORMAT = "2006-01-02 15:04:05"
 )
+
+var sizeUnits = []struct {
+	size uint64
+	name string
+}{
+	{PiB, "PiB"},
+	{TiB, "TiB"},
+	{GiB, "GiB"},
+	{MiB, "MiB"},
+	{KiB, "KiB"},
+}
+
+// FormatBytes formats size in bytes with the largest binary unit not
+// greater than it, e.g. 1.50 GiB, 500.00 MiB and 100 B.
+func FormatBytes(size uint64) string {
+	for _, unit := range sizeUnits {
+		if size >= unit.size {
+			return fmt.Sprintf("%.2f %s", float64(size)/float64(unit.size), unit.name)
+		}
+	}
+	return fmt.Sprintf("%d B", size)
+}
+
+// ToGiB converts size in bytes to GiB without truncation
+func ToGiB(size uint64) float64 {
+	return float64(size) / GiB
+}
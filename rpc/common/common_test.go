@@ -0,0 +1,48 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package common
+
+import "testing"
+
+func TestFormatBytes(t *testing.T) {
+	cases := []struct {
+		size     uint64
+		expected string
+	}{
+		{0, "0 B"},
+		{100, "100 B"},
+		{KiB, "1.00 KiB"},
+		{500 * MiB, "500.00 MiB"},
+		{GiB + GiB/2, "1.50 GiB"},
+		{3 * TiB, "3.00 TiB"},
+		{2 * PiB, "2.00 PiB"},
+	}
+	for _, c := range cases {
+		if actual := FormatBytes(c.size); actual != c.expected {
+			t.Errorf("TestFormatBytes size = %d, expected = %s, actual = %s", c.size, c.expected, actual)
+		}
+	}
+	if actual := ToGiB(500 * MiB); actual < 0.48 || actual > 0.49 {
+		t.Errorf("TestFormatBytes expected 500MiB = 0.488GiB, actual = %f", actual)
+	}
+}
@@ -0,0 +1,197 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+// Credential is the user which owns files, Password is needed only if the mds
+// checks signature of the user, e.g. the root user.
+type Credential struct {
+	Owner    string
+	Password string
+}
+
+// CalcSignature computes the signature of owner at date in microseconds,
+// it is base64(hmac-sha256(password, "date:owner")) as curve does.
+func CalcSignature(owner, password string, date uint64) string {
+	mac := hmac.New(sha256.New, []byte(password))
+	mac.Write([]byte(fmt.Sprintf("%d:%s", date, owner)))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Sign returns the signature and date of now, the signature is empty if
+// Password is empty. Signatures expire on mds, so sign every request.
+func (c Credential) Sign() (string, uint64) {
+	date := uint64(time.Now().UnixMicro())
+	if c.Password == "" {
+		return "", date
+	}
+	return CalcSignature(c.Owner, c.Password, date), date
+}
+
+// AuthClient is the authenticator of MdsClient, it signs namespace rpcs with
+// its credential so callers need not pass owner, sig and date.
+type AuthClient struct {
+	cli        *MdsClient
+	credential Credential
+}
+
+// Auth returns the authenticator using MdsClientOption.Owner and MdsClientOption.Password.
+func (cli *MdsClient) Auth() *AuthClient {
+	return cli.WithCredential(cli.credential)
+}
+
+// WithCredential returns the authenticator using credential.
+func (cli *MdsClient) WithCredential(credential Credential) *AuthClient {
+	return &AuthClient{
+		cli:        cli,
+		credential: credential,
+	}
+}
+
+func (a *AuthClient) ListDir(ctx context.Context, dirname string) ([]FileInfo, error) {
+	sig, date := a.credential.Sign()
+	return a.cli.ListDirWithContext(ctx, dirname, a.credential.Owner, sig, date)
+}
+
+func (a *AuthClient) GetFileInfo(ctx context.Context, filename string) (FileInfo, error) {
+	sig, date := a.credential.Sign()
+	return a.cli.GetFileInfoWithContext(ctx, filename, a.credential.Owner, sig, date)
+}
+
+// fileId is checked by mds if it is not 0
+func (a *AuthClient) DeleteFile(ctx context.Context, filename string, fileId uint64, forceDelete bool) error {
+	sig, date := a.credential.Sign()
+	return a.cli.DeleteFileWithContext(ctx, filename, a.credential.Owner, sig, fileId, date, forceDelete)
+}
+
+func (a *AuthClient) RecoverFile(ctx context.Context, filename string, fileId uint64) error {
+	sig, date := a.credential.Sign()
+	return a.cli.RecoverFileWithContext(ctx, filename, a.credential.Owner, sig, fileId, date)
+}
+
+func (a *AuthClient) CreateFile(ctx context.Context, filename, ftype string, length, stripeUnit, stripeCount uint64) error {
+	sig, date := a.credential.Sign()
+	return a.cli.CreateFileWithContext(ctx, filename, ftype, a.credential.Owner, sig, length, date, stripeUnit, stripeCount)
+}
+
+func (a *AuthClient) ExtendFile(ctx context.Context, filename string, newSize uint64) error {
+	sig, date := a.credential.Sign()
+	return a.cli.ExtendFileWithContext(ctx, filename, a.credential.Owner, sig, newSize, date)
+}
+
+func (a *AuthClient) RenameFile(ctx context.Context, oldFilename, newFilename string, oldFileId, newFileId uint64) error {
+	sig, date := a.credential.Sign()
+	return a.cli.RenameFileWithContext(ctx, oldFilename, newFilename, a.credential.Owner, sig, oldFileId, newFileId, date)
+}
+
+// the credential must be the root user
+func (a *AuthClient) ChangeOwner(ctx context.Context, filename, newOwner string) error {
+	sig, date := a.credential.Sign()
+	return a.cli.ChangeOwnerWithContext(ctx, filename, newOwner, a.credential.Owner, sig, date)
+}
+
+func (a *AuthClient) UpdateFileThrottleParams(ctx context.Context, filename string, params ThrottleParams) error {
+	sig, date := a.credential.Sign()
+	return a.cli.UpdateFileThrottleParamsWithContext(ctx, filename, a.credential.Owner, sig, date, params)
+}
+
+func (a *AuthClient) CreateSnapShot(ctx context.Context, filename string) (FileInfo, error) {
+	sig, date := a.credential.Sign()
+	return a.cli.CreateSnapShotWithContext(ctx, filename, a.credential.Owner, sig, date)
+}
+
+func (a *AuthClient) ListSnapShot(ctx context.Context, filename string, seqs []uint64) ([]FileInfo, error) {
+	sig, date := a.credential.Sign()
+	return a.cli.ListSnapShotWithContext(ctx, filename, a.credential.Owner, sig, date, seqs)
+}
+
+func (a *AuthClient) GetSnapShotFileInfo(ctx context.Context, filename string, seq uint64) (FileInfo, error) {
+	sig, date := a.credential.Sign()
+	return a.cli.GetSnapShotFileInfoWithContext(ctx, filename, a.credential.Owner, sig, seq, date)
+}
+
+func (a *AuthClient) CheckSnapShotStatus(ctx context.Context, filename string, seq uint64) (SnapShotStatus, error) {
+	sig, date := a.credential.Sign()
+	return a.cli.CheckSnapShotStatusWithContext(ctx, filename, a.credential.Owner, sig, seq, date)
+}
+
+func (a *AuthClient) DeleteSnapShot(ctx context.Context, filename string, seq uint64) error {
+	sig, date := a.credential.Sign()
+	return a.cli.DeleteSnapShotWithContext(ctx, filename, a.credential.Owner, sig, seq, date)
+}
+
+func (a *AuthClient) CreateCloneFile(ctx context.Context, filename, cloneSource string, length, seq uint64,
+	chunkSize uint32) (FileInfo, error) {
+	sig, date := a.credential.Sign()
+	return a.cli.CreateCloneFileWithContext(ctx, filename, a.credential.Owner, sig, cloneSource, length, seq, chunkSize, date)
+}
+
+func (a *AuthClient) SetCloneFileStatus(ctx context.Context, filename string, fileId uint64, status string) error {
+	sig, date := a.credential.Sign()
+	return a.cli.SetCloneFileStatusWithContext(ctx, filename, a.credential.Owner, sig, fileId, status, date)
+}
+
+func (a *AuthClient) WaitCloneFileStatus(ctx context.Context, filename, status string) (FileInfo, error) {
+	return a.cli.WaitCloneFileStatusWithContext(ctx, filename, a.credential, status)
+}
+
+func (a *AuthClient) GetSegment(ctx context.Context, filename string, offset uint64) (Segment, error) {
+	sig, date := a.credential.Sign()
+	return a.cli.GetSegmentWithContext(ctx, filename, a.credential.Owner, sig, offset, date)
+}
+
+func (a *AuthClient) DeAllocateSegment(ctx context.Context, filename string, offset uint64) error {
+	sig, date := a.credential.Sign()
+	return a.cli.DeAllocateSegmentWithContext(ctx, filename, a.credential.Owner, sig, offset, date)
+}
+
+func (a *AuthClient) GetChunkLocations(ctx context.Context, filename string, offset, length uint64) ([]ChunkLocation, error) {
+	sig, date := a.credential.Sign()
+	return a.cli.GetChunkLocationsWithContext(ctx, filename, a.credential.Owner, sig, offset, length, date)
+}
+
+func (a *AuthClient) Walk(ctx context.Context, root string, option WalkOption, fn WalkFunc) error {
+	option.Credential = a.credential
+	return a.cli.WalkWithContext(ctx, root, option, fn)
+}
+
+func (a *AuthClient) ListRecycleBin(ctx context.Context) ([]RecycleBinEntry, error) {
+	sig, date := a.credential.Sign()
+	return a.cli.ListRecycleBinWithContext(ctx, a.credential.Owner, sig, date)
+}
+
+func (a *AuthClient) RecoverRecycleBinFile(ctx context.Context, filename string) (RecycleBinEntry, error) {
+	return a.cli.RecoverRecycleBinFileWithContext(ctx, filename, a.credential)
+}
+
+func (a *AuthClient) ExpireRecycleBin(ctx context.Context, expiration time.Duration) ([]RecycleBinEntry, error) {
+	return a.cli.ExpireRecycleBinWithContext(ctx, a.credential, expiration)
+}
@@ -0,0 +1,62 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
+)
+
+func TestCalcSignature(t *testing.T) {
+	sig := CalcSignature("root", "root_password", 1600000000000000)
+	expected := "+yzh+boEwhCHOE694fKTB60hhtBQ9KrYWOSgYGsLRBo="
+	if sig != expected {
+		t.Errorf("TestCalcSignature expected signature = %s, actual signature = %s", expected, sig)
+	}
+
+	sig, date := Credential{Owner: "curve"}.Sign()
+	if sig != "" || date == 0 {
+		t.Errorf("TestCalcSignature expected empty signature without password, actual signature = %s, date = %d",
+			sig, date)
+	}
+}
+
+func TestAuthClient(t *testing.T) {
+	option := fsClientOption
+	option.Owner = root_owner
+	option.Password = root_password
+	mdsClient := NewMdsClient(option)
+	defer mdsClient.Close()
+	if err := mdsClient.Auth().ChangeOwner(context.Background(), file_name, "tenant"); err != nil {
+		t.Errorf("TestAuthClient change owner rpc failed, error = %v", err)
+	}
+
+	auth := mdsClient.WithCredential(Credential{Owner: root_owner, Password: "wrong"})
+	err := auth.ChangeOwner(context.Background(), file_name, "tenant")
+	if !errors.Is(err, rpcerr.ErrOwnerAuthFail) {
+		t.Errorf("TestAuthClient expected ErrOwnerAuthFail, actual error = %v", err)
+	}
+}
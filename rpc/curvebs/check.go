@@ -0,0 +1,242 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	// problems of copyset
+	COPYSET_UNDER_REPLICATED = "under-replicated"
+	COPYSET_LEADERLESS       = "leaderless"
+	COPYSET_LAGGING          = "lagging"
+	COPYSET_PEER_MISMATCH    = "peer-set-mismatch"
+	// a copyset without problems
+	COPYSET_HEALTHY = "healthy"
+
+	DEFAULT_APPLIED_INDEX_GAP = 1000
+)
+
+type CheckCopysetsOption struct {
+	// the copyset is lagging if the gap of applied index between replicas
+	// is beyond MaxAppliedIndexGap, DEFAULT_APPLIED_INDEX_GAP if not set
+	MaxAppliedIndexGap int64
+	// replicas expected of each copyset, only the replicas recorded by mds are expected if not set
+	ReplicaNum int
+	// copyset status rpcs in flight, DEFAULT_COPYSET_STATUS_CONCURRENCY if not set
+	Concurrency int
+}
+
+// CopysetCheck is the status of all replicas of a copyset recorded by mds
+type CopysetCheck struct {
+	LogicalPoolId uint32                `json:"logicalPoolId"`
+	CopysetId     uint32                `json:"copysetId"`
+	Replicas      []ChunkServerLocation `json:"replicas"`
+	Statuses      []CopysetStatus       `json:"statuses"`
+	Failures      []ReplicaError        `json:"failures"`
+	// empty if the copyset is healthy
+	Problems []string `json:"problems"`
+}
+
+// PoolCheck counts the copysets of a logical pool by problem, a copyset is
+// counted once for each of its problems, or as COPYSET_HEALTHY.
+type PoolCheck struct {
+	LogicalPoolId uint32         `json:"logicalPoolId"`
+	Total         int            `json:"total"`
+	Counts        map[string]int `json:"counts"`
+}
+
+type CopysetsReport struct {
+	Copysets []CopysetCheck `json:"copysets"`
+	Pools    []PoolCheck    `json:"pools"`
+	Total    int            `json:"total"`
+	Counts   map[string]int `json:"counts"`
+}
+
+func (c *CopysetCheck) Healthy() bool {
+	return len(c.Problems) == 0
+}
+
+// check all copysets in cluster like curve_ops_tool copysets-status: the
+// replicas of each copyset recorded by mds are asked for their raft status.
+// A copyset is
+//   - under-replicated if fewer replicas than expected are serving,
+//   - leaderless if no replica is the leader or replicas disagree on the leader,
+//   - lagging if applied index of replicas differ beyond MaxAppliedIndexGap,
+//   - peer-set-mismatch if the leader is not a replica recorded by mds.
+//
+// The chunkserver doesn't tell its peers, so peers beyond the leader are not compared.
+func (cli *ChunkserverClient) CheckCopysets(mds *MdsClient, option CheckCopysetsOption) (CopysetsReport, error) {
+	return cli.CheckCopysetsWithContext(context.Background(), mds, option)
+}
+
+func (cli *ChunkserverClient) CheckCopysetsWithContext(ctx context.Context, mds *MdsClient,
+	option CheckCopysetsOption) (CopysetsReport, error) {
+	if option.MaxAppliedIndexGap <= 0 {
+		option.MaxAppliedIndexGap = DEFAULT_APPLIED_INDEX_GAP
+	}
+	if option.Concurrency <= 0 {
+		option.Concurrency = DEFAULT_COPYSET_STATUS_CONCURRENCY
+	}
+	copysets, err := mds.GetCopySetsInClusterWithContext(ctx)
+	if err != nil {
+		return CopysetsReport{}, err
+	}
+	pools := make(map[uint32][]uint32)
+	for _, cs := range copysets {
+		pools[cs.LogicalPoolId] = append(pools[cs.LogicalPoolId], cs.CopysetId)
+	}
+	checks := []CopysetCheck{}
+	for poolId, copysetIds := range pools {
+		infos, err := mds.GetChunkServerListInCopySetsWithContext(ctx, poolId, copysetIds)
+		if err != nil {
+			return CopysetsReport{}, fmt.Errorf("logical pool id: %d; %w", poolId, err)
+		}
+		for _, info := range infos {
+			checks = append(checks, CopysetCheck{
+				LogicalPoolId: poolId,
+				CopysetId:     info.CopysetId,
+				Replicas:      info.CsLocs,
+				Statuses:      []CopysetStatus{},
+				Failures:      []ReplicaError{},
+			})
+		}
+	}
+	sort.Slice(checks, func(i, j int) bool {
+		if checks[i].LogicalPoolId != checks[j].LogicalPoolId {
+			return checks[i].LogicalPoolId < checks[j].LogicalPoolId
+		}
+		return checks[i].CopysetId < checks[j].CopysetId
+	})
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, option.Concurrency)
+	for i := range checks {
+		for _, replica := range checks[i].Replicas {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(check *CopysetCheck, replica ChunkServerLocation) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				status, err := cli.GetCopysetStatusWithContext(ctx, NewPeer(replica.HostIp, replica.Port),
+					check.LogicalPoolId, check.CopysetId)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					check.Failures = append(check.Failures, ReplicaError{Replica: replica, Err: err})
+					return
+				}
+				check.Statuses = append(check.Statuses, status)
+			}(&checks[i], replica)
+		}
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return CopysetsReport{}, err
+	}
+
+	report := CopysetsReport{
+		Copysets: checks,
+		Pools:    []PoolCheck{},
+		Counts:   make(map[string]int),
+	}
+	for i := range report.Copysets {
+		check := &report.Copysets[i]
+		check.Problems = classifyCopyset(check, option)
+		if len(report.Pools) == 0 || report.Pools[len(report.Pools)-1].LogicalPoolId != check.LogicalPoolId {
+			report.Pools = append(report.Pools, PoolCheck{
+				LogicalPoolId: check.LogicalPoolId,
+				Counts:        make(map[string]int),
+			})
+		}
+		pool := &report.Pools[len(report.Pools)-1]
+		pool.Total++
+		report.Total++
+		problems := check.Problems
+		if len(problems) == 0 {
+			problems = []string{COPYSET_HEALTHY}
+		}
+		for _, problem := range problems {
+			pool.Counts[problem]++
+			report.Counts[problem]++
+		}
+	}
+	return report, nil
+}
+
+func classifyCopyset(check *CopysetCheck, option CheckCopysetsOption) []string {
+	problems := []string{}
+	expected := len(check.Replicas)
+	if option.ReplicaNum > expected {
+		expected = option.ReplicaNum
+	}
+	serving := 0
+	leaders := make(map[string]bool)
+	var minApplied, maxApplied int64
+	for i, status := range check.Statuses {
+		switch status.State {
+		case STATE_LEADER, STATE_FOLLOWER, STATE_TRANSFERRING:
+			serving++
+		}
+		if addr, ok := leaderEndpoint(status.Leader); ok {
+			leaders[addr] = true
+		}
+		if i == 0 || status.KnownAppliedIndex < minApplied {
+			minApplied = status.KnownAppliedIndex
+		}
+		if i == 0 || status.KnownAppliedIndex > maxApplied {
+			maxApplied = status.KnownAppliedIndex
+		}
+	}
+
+	if serving < expected {
+		problems = append(problems, COPYSET_UNDER_REPLICATED)
+	}
+	if len(leaders) != 1 {
+		problems = append(problems, COPYSET_LEADERLESS)
+	}
+	if maxApplied-minApplied > option.MaxAppliedIndexGap {
+		problems = append(problems, COPYSET_LAGGING)
+	}
+	for leader := range leaders {
+		recorded := false
+		for _, replica := range check.Replicas {
+			if leader == fmt.Sprintf("%s:%d", replica.HostIp, replica.Port) {
+				recorded = true
+				break
+			}
+		}
+		if !recorded {
+			problems = append(problems, COPYSET_PEER_MISMATCH)
+			break
+		}
+	}
+	return problems
+}
@@ -0,0 +1,165 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/chunk"
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
+)
+
+const (
+	// brpc carries chunk data in the attachment, which has no counterpart in
+	// grpc, so data is carried in binary metadata instead. Chunkservers serving
+	// grpc must do the same, and the size of data is bounded by the max header
+	// list size of grpc.
+	CHUNK_ATTACHMENT_KEY = "curve-attachment-bin"
+	// times to follow the redirections of non-leader chunkservers
+	MAX_CHUNK_REDIRECT = 3
+
+	// apis
+	READ_CHUNK          = "ReadChunk"
+	WRITE_CHUNK         = "WriteChunk"
+	READ_CHUNK_SNAPSHOT = "ReadChunkSnapshot"
+	GET_CHUNK_INFO      = "GetChunkInfo"
+)
+
+// ChunkInfo is the versions of chunk, Sn is that of chunk itself, and
+// SnapSns are those of its snapshots.
+type ChunkInfo struct {
+	Sn      uint64   `json:"sn"`
+	SnapSns []uint64 `json:"snapSns"`
+}
+
+func checkChunkData(name string, data []byte, length uint32) ([]byte, error) {
+	if len(data) != int(length) {
+		return nil, fmt.Errorf("%s: expected %d bytes of data, actual %d bytes", name, length, len(data))
+	}
+	return data, nil
+}
+
+// read [offset, offset+length) of chunk from peer, the rpc is redirected
+// to the leader if peer is not.
+func (cli *ChunkserverClient) ReadChunk(peer Peer, logicalPoolId, copysetId uint32, chunkId uint64,
+	offset, length uint32) ([]byte, error) {
+	return cli.ReadChunkWithContext(context.Background(), peer, logicalPoolId, copysetId, chunkId, offset, length)
+}
+
+func (cli *ChunkserverClient) ReadChunkWithContext(ctx context.Context, peer Peer, logicalPoolId, copysetId uint32,
+	chunkId uint64, offset, length uint32) ([]byte, error) {
+	request := &chunk.ChunkRequest{
+		OpType:      chunk.CHUNK_OP_TYPE_CHUNK_OP_READ.Enum(),
+		LogicPoolId: &logicalPoolId,
+		CopysetId:   &copysetId,
+		ChunkId:     &chunkId,
+		Offset:      &offset,
+		Size:        &length,
+	}
+	_, data, err := callChunk(ctx, cli, peer, READ_CHUNK, true, chunk.ChunkServiceClient.ReadChunk, request, nil)
+	if err != nil {
+		return nil, err
+	}
+	return checkChunkData(READ_CHUNK, data, length)
+}
+
+// write data at offset of chunk whose version is sn through peer, the rpc is
+// redirected to the leader if peer is not.
+func (cli *ChunkserverClient) WriteChunk(peer Peer, logicalPoolId, copysetId uint32, chunkId, sn uint64,
+	offset uint32, data []byte) error {
+	return cli.WriteChunkWithContext(context.Background(), peer, logicalPoolId, copysetId, chunkId, sn, offset, data)
+}
+
+func (cli *ChunkserverClient) WriteChunkWithContext(ctx context.Context, peer Peer, logicalPoolId, copysetId uint32,
+	chunkId, sn uint64, offset uint32, data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%s: %w: empty data", WRITE_CHUNK, rpcerr.ErrInvalidParam)
+	}
+	size := uint32(len(data))
+	request := &chunk.ChunkRequest{
+		OpType:      chunk.CHUNK_OP_TYPE_CHUNK_OP_WRITE.Enum(),
+		LogicPoolId: &logicalPoolId,
+		CopysetId:   &copysetId,
+		ChunkId:     &chunkId,
+		Offset:      &offset,
+		Size:        &size,
+		Sn:          &sn,
+	}
+	_, _, err := callChunk(ctx, cli, peer, WRITE_CHUNK, false, chunk.ChunkServiceClient.WriteChunk, request, data)
+	return err
+}
+
+// read [offset, offset+length) of the snapshot of chunk whose version is sn
+func (cli *ChunkserverClient) ReadChunkSnapshot(peer Peer, logicalPoolId, copysetId uint32, chunkId, sn uint64,
+	offset, length uint32) ([]byte, error) {
+	return cli.ReadChunkSnapshotWithContext(context.Background(), peer, logicalPoolId, copysetId, chunkId, sn,
+		offset, length)
+}
+
+func (cli *ChunkserverClient) ReadChunkSnapshotWithContext(ctx context.Context, peer Peer, logicalPoolId,
+	copysetId uint32, chunkId, sn uint64, offset, length uint32) ([]byte, error) {
+	request := &chunk.ChunkRequest{
+		OpType:      chunk.CHUNK_OP_TYPE_CHUNK_OP_READ_SNAP.Enum(),
+		LogicPoolId: &logicalPoolId,
+		CopysetId:   &copysetId,
+		ChunkId:     &chunkId,
+		Offset:      &offset,
+		Size:        &length,
+		Sn:          &sn,
+	}
+	_, data, err := callChunk(ctx, cli, peer, READ_CHUNK_SNAPSHOT, true, chunk.ChunkServiceClient.ReadChunkSnapshot,
+		request, nil)
+	if err != nil {
+		return nil, err
+	}
+	return checkChunkData(READ_CHUNK_SNAPSHOT, data, length)
+}
+
+// get the versions of chunk, a chunk not written yet has no versions
+func (cli *ChunkserverClient) GetChunkInfo(peer Peer, logicalPoolId, copysetId uint32, chunkId uint64) (ChunkInfo, error) {
+	return cli.GetChunkInfoWithContext(context.Background(), peer, logicalPoolId, copysetId, chunkId)
+}
+
+func (cli *ChunkserverClient) GetChunkInfoWithContext(ctx context.Context, peer Peer, logicalPoolId, copysetId uint32,
+	chunkId uint64) (ChunkInfo, error) {
+	request := &chunk.GetChunkInfoRequest{
+		LogicPoolId: &logicalPoolId,
+		CopysetId:   &copysetId,
+		ChunkId:     &chunkId,
+	}
+	response, _, err := callChunk(ctx, cli, peer, GET_CHUNK_INFO, true, chunk.ChunkServiceClient.GetChunkInfo,
+		request, nil)
+	if err != nil {
+		return ChunkInfo{}, err
+	}
+	info := ChunkInfo{SnapSns: []uint64{}}
+	for i, sn := range response.GetChunkSn() {
+		if i == 0 {
+			info.Sn = sn
+		} else {
+			info.SnapSns = append(info.SnapSns, sn)
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,223 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/chunk"
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	followerPort    = ":12906"
+	chunkLeaderPort = ":12907"
+)
+
+var (
+	followerGs    *grpc.Server
+	chunkLeaderGs *grpc.Server
+	chunkLeader   = &chunkServer{chunks: make(map[uint64]*fakeChunk)}
+
+	follower_peer            = NewPeer("127.0.0.1", 12906)
+	chunk_leader_peer        = NewPeer("127.0.0.1", 12907)
+	chunk_id          uint64 = 1
+	// the follower knows no leader of it
+	lost_chunk_id uint64 = 99
+)
+
+type fakeChunk struct {
+	sn     uint64
+	data   []byte
+	snapSn uint64
+	snap   []byte
+}
+
+// chunkServer redirects to redirect if it is set, otherwise it keeps chunks
+// in memory and snapshots a chunk when it is written with a newer sn.
+type chunkServer struct {
+	chunk.UnimplementedChunkServiceServer
+	redirect string
+	mu       sync.Mutex
+	chunks   map[uint64]*fakeChunk
+}
+
+func (s *chunkServer) redirected(chunkId uint64) (*chunk.ChunkResponse, bool) {
+	if s.redirect == "" {
+		return nil, false
+	}
+	response := &chunk.ChunkResponse{Status: chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_REDIRECTED.Enum()}
+	if chunkId != lost_chunk_id {
+		response.Redirect = &s.redirect
+	}
+	return response, true
+}
+
+func (s *chunkServer) read(ctx context.Context, req *chunk.ChunkRequest, snapshot bool) (*chunk.ChunkResponse, error) {
+	if response, ok := s.redirected(req.GetChunkId()); ok {
+		return response, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var data []byte
+	c, ok := s.chunks[req.GetChunkId()]
+	if ok && snapshot {
+		ok = c.snap != nil && c.snapSn == req.GetSn()
+		data = c.snap
+	} else if ok {
+		data = c.data
+	}
+	if !ok {
+		return &chunk.ChunkResponse{Status: chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_CHUNK_NOTEXIST.Enum()}, nil
+	}
+	end := req.GetOffset() + req.GetSize()
+	if int(end) > len(data) {
+		return &chunk.ChunkResponse{Status: chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_INVALID_REQUEST.Enum()}, nil
+	}
+	header := metadata.Pairs(CHUNK_ATTACHMENT_KEY, string(data[req.GetOffset():end]))
+	if err := grpc.SetHeader(ctx, header); err != nil {
+		return nil, err
+	}
+	return &chunk.ChunkResponse{Status: chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_SUCCESS.Enum()}, nil
+}
+
+func (s *chunkServer) ReadChunk(ctx context.Context, req *chunk.ChunkRequest) (*chunk.ChunkResponse, error) {
+	return s.read(ctx, req, false)
+}
+
+func (s *chunkServer) ReadChunkSnapshot(ctx context.Context, req *chunk.ChunkRequest) (*chunk.ChunkResponse, error) {
+	return s.read(ctx, req, true)
+}
+
+func (s *chunkServer) WriteChunk(ctx context.Context, req *chunk.ChunkRequest) (*chunk.ChunkResponse, error) {
+	if response, ok := s.redirected(req.GetChunkId()); ok {
+		return response, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(CHUNK_ATTACHMENT_KEY)
+	if len(values) != 1 || len(values[0]) != int(req.GetSize()) {
+		return &chunk.ChunkResponse{Status: chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_INVALID_REQUEST.Enum()}, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.chunks[req.GetChunkId()]
+	if !ok {
+		c = &fakeChunk{sn: req.GetSn()}
+		s.chunks[req.GetChunkId()] = c
+	}
+	if req.GetSn() > c.sn {
+		c.snap, c.snapSn = append([]byte{}, c.data...), c.sn
+		c.sn = req.GetSn()
+	}
+	end := int(req.GetOffset()) + len(values[0])
+	if end > len(c.data) {
+		c.data = append(c.data, make([]byte, end-len(c.data))...)
+	}
+	copy(c.data[req.GetOffset():], values[0])
+	return &chunk.ChunkResponse{Status: chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_SUCCESS.Enum()}, nil
+}
+
+func (s *chunkServer) GetChunkInfo(ctx context.Context, req *chunk.GetChunkInfoRequest) (*chunk.GetChunkInfoResponse, error) {
+	if s.redirect != "" {
+		return &chunk.GetChunkInfoResponse{
+			Status:   chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_REDIRECTED.Enum(),
+			Redirect: &s.redirect,
+		}, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	response := &chunk.GetChunkInfoResponse{Status: chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_SUCCESS.Enum()}
+	if c, ok := s.chunks[req.GetChunkId()]; ok {
+		response.ChunkSn = append(response.ChunkSn, c.sn)
+		if c.snap != nil {
+			response.ChunkSn = append(response.ChunkSn, c.snapSn)
+		}
+	}
+	return response, nil
+}
+
+func serveChunk(port string, server *chunkServer) *grpc.Server {
+	gs := grpc.NewServer()
+	chunk.RegisterChunkServiceServer(gs, server)
+	go func() {
+		lis, err := net.Listen("tcp", port)
+		if err != nil {
+			fmt.Printf("failed to listen: %v", err)
+		}
+		if err := gs.Serve(lis); err != nil {
+			fmt.Printf("failed to serve: %v", err)
+		}
+	}()
+	return gs
+}
+
+func init() {
+	followerGs = serveChunk(followerPort, &chunkServer{redirect: chunk_leader_peer.Address})
+	chunkLeaderGs = serveChunk(chunkLeaderPort, chunkLeader)
+}
+
+func TestChunk(t *testing.T) {
+	client := NewChunkserverClient(csClientOption)
+	defer client.Close()
+
+	// written through the follower, and then overwritten with a newer sn
+	if err := client.WriteChunk(follower_peer, logical_pool_id, copyset_id, chunk_id, 1, 0, []byte("hello")); err != nil {
+		t.Fatalf("TestChunk write rpc failed, error = %v", err)
+	}
+	if err := client.WriteChunk(chunk_leader_peer, logical_pool_id, copyset_id, chunk_id, 2, 1, []byte("ELLO")); err != nil {
+		t.Fatalf("TestChunk write with new sn rpc failed, error = %v", err)
+	}
+
+	data, err := client.ReadChunk(follower_peer, logical_pool_id, copyset_id, chunk_id, 0, 5)
+	if err != nil || string(data) != "hELLO" {
+		t.Errorf("TestChunk expected data = hELLO, actual data = %q, error = %v", data, err)
+	}
+	data, err = client.ReadChunkSnapshot(follower_peer, logical_pool_id, copyset_id, chunk_id, 1, 1, 3)
+	if err != nil || string(data) != "ell" {
+		t.Errorf("TestChunk expected snapshot data = ell, actual data = %q, error = %v", data, err)
+	}
+	info, err := client.GetChunkInfo(follower_peer, logical_pool_id, copyset_id, chunk_id)
+	if err != nil || !reflect.DeepEqual(info, ChunkInfo{Sn: 2, SnapSns: []uint64{1}}) {
+		t.Errorf("TestChunk expected sn = 2 and snapshot sn = 1, actual info = %+v, error = %v", info, err)
+	}
+
+	_, err = client.ReadChunk(chunk_leader_peer, logical_pool_id, copyset_id, chunk_id+1, 0, 5)
+	if !errors.Is(err, rpcerr.ErrChunkNotExists) {
+		t.Errorf("TestChunk expected ErrChunkNotExists, actual error = %v", err)
+	}
+	_, err = client.ReadChunk(follower_peer, logical_pool_id, copyset_id, lost_chunk_id, 0, 5)
+	if !errors.Is(err, rpcerr.ErrRedirected) {
+		t.Errorf("TestChunk expected ErrRedirected, actual error = %v", err)
+	}
+	if err := client.WriteChunk(chunk_leader_peer, logical_pool_id, copyset_id, chunk_id, 2, 0, nil); !errors.Is(err, rpcerr.ErrInvalidParam) {
+		t.Errorf("TestChunk expected ErrInvalidParam, actual error = %v", err)
+	}
+}
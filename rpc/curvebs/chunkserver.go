@@ -0,0 +1,353 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/chunk"
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/cli2"
+	pbcommon "github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/common"
+	"github.com/SeanHai/curve-go-rpc/rpc/baserpc"
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
+)
+
+const (
+	// apis
+	GET_LEADER      = "GetLeader"
+	ADD_PEER        = "AddPeer"
+	REMOVE_PEER     = "RemovePeer"
+	CHANGE_PEERS    = "ChangePeers"
+	TRANSFER_LEADER = "TransferLeader"
+	RESET_PEER      = "ResetPeer"
+	SNAPSHOT        = "Snapshot"
+	SNAPSHOT_ALL    = "SnapshotAll"
+)
+
+type ChunkserverClientOption struct {
+	TimeoutMs  int
+	RetryTimes uint32
+	// idle connections are closed after ConnIdleTimeoutMs, the client creates its own pool
+	// if it is set, 0 means baserpc.DEFAULT_CONN_IDLE_TIMEOUT
+	ConnIdleTimeoutMs int
+	// share connections with other clients, baserpc.DefaultConnPool() is used if nil
+	// and ConnIdleTimeoutMs is not set
+	ConnPool *baserpc.ConnPool
+	// retry backoff, 0 means baserpc.DEFAULT_RETRY_BACKOFF and baserpc.DEFAULT_RETRY_MAX_BACKOFF
+	RetryBackoffMs    int
+	RetryMaxBackoffMs int
+	// overall deadline of an rpc including retries, 0 means no limit besides RetryTimes
+	RetryDeadlineMs int
+	// also retry raft configuration changes, such as AddPeer and TransferLeader
+	RetryNonIdempotent bool
+}
+
+// ChunkserverClient sends each rpc to the chunkserver given by its caller
+// rather than a set of replicas.
+type ChunkserverClient struct {
+	baseClient *baserpc.BaseRpc
+	ownPool    bool
+}
+
+// Peer is a raft member of copyset, its Address is ip:port:index
+type Peer struct {
+	Id            uint64 `json:"id"`
+	ChunkServerId uint64 `json:"chunkServerId"`
+	Address       string `json:"address"`
+}
+
+func NewChunkserverClient(option ChunkserverClientOption) *ChunkserverClient {
+	pool, ownPool := clientConnPool(option.ConnPool, option.ConnIdleTimeoutMs)
+	return &ChunkserverClient{
+		baseClient: &baserpc.BaseRpc{
+			Timeout:    time.Duration(option.TimeoutMs * int(time.Millisecond)),
+			RetryTimes: option.RetryTimes,
+			RetryPolicy: baserpc.RetryPolicy{
+				Backoff:            time.Duration(option.RetryBackoffMs * int(time.Millisecond)),
+				MaxBackoff:         time.Duration(option.RetryMaxBackoffMs * int(time.Millisecond)),
+				Deadline:           time.Duration(option.RetryDeadlineMs * int(time.Millisecond)),
+				RetryableStatus:    isRetryableChunkStatus,
+				RetryNonIdempotent: option.RetryNonIdempotent,
+			},
+			Pool: pool,
+		},
+		ownPool: ownPool,
+	}
+}
+
+// the chunkserver is too busy to handle the request, it is worth retrying
+func isRetryableChunkStatus(response interface{}) bool {
+	res, ok := response.(chunkResponse)
+	return ok && res.GetStatus() == chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_OVERLOAD
+}
+
+// Close releases the connections unless the pool is shared
+func (cli *ChunkserverClient) Close() {
+	if cli.ownPool {
+		cli.baseClient.Pool.Close()
+	}
+}
+
+// NewPeer returns the peer of chunkserver at ip:port
+func NewPeer(ip string, port uint32) Peer {
+	return Peer{Address: fmt.Sprintf("%s:%d:0", ip, port)}
+}
+
+// Endpoint returns ip:port of the peer
+func (p Peer) Endpoint() (string, error) {
+	items := strings.Split(p.Address, ":")
+	if len(items) < 2 {
+		return "", fmt.Errorf("%w: peer address %s", rpcerr.ErrInvalidParam, p.Address)
+	}
+	if _, err := strconv.ParseUint(items[1], 10, 32); err != nil {
+		return "", fmt.Errorf("%w: peer address %s", rpcerr.ErrInvalidParam, p.Address)
+	}
+	return items[0] + ":" + items[1], nil
+}
+
+func (p Peer) toProto() *pbcommon.Peer {
+	peer := &pbcommon.Peer{Address: &p.Address}
+	if p.Id != 0 {
+		peer.Id = &p.Id
+	}
+	if p.ChunkServerId != 0 {
+		peer.ChunkServerID = &p.ChunkServerId
+	}
+	return peer
+}
+
+func getPeer(p *pbcommon.Peer) Peer {
+	return Peer{
+		Id:            p.GetId(),
+		ChunkServerId: p.GetChunkServerID(),
+		Address:       p.GetAddress(),
+	}
+}
+
+func getPeers(peers []*pbcommon.Peer) []Peer {
+	infos := []Peer{}
+	for _, p := range peers {
+		infos = append(infos, getPeer(p))
+	}
+	return infos
+}
+
+func toProtoPeers(peers []Peer) []*pbcommon.Peer {
+	infos := []*pbcommon.Peer{}
+	for _, p := range peers {
+		infos = append(infos, p.toProto())
+	}
+	return infos
+}
+
+// the rpc context addressing peer only
+func peerRpcContext(peer Peer, name string, idempotent bool) (*baserpc.RpcContext, error) {
+	addr, err := peer.Endpoint()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if idempotent {
+		return baserpc.NewRpcContext([]string{addr}, name), nil
+	}
+	return baserpc.NewNonIdempotentRpcContext([]string{addr}, name), nil
+}
+
+// get the leader of copyset from its member peer
+func (cli *ChunkserverClient) GetLeader(peer Peer, logicalPoolId, copysetId uint32) (Peer, error) {
+	return cli.GetLeaderWithContext(context.Background(), peer, logicalPoolId, copysetId)
+}
+
+func (cli *ChunkserverClient) GetLeaderWithContext(ctx context.Context, peer Peer, logicalPoolId, copysetId uint32) (Peer, error) {
+	rpcCtx, err := peerRpcContext(peer, GET_LEADER, true)
+	if err != nil {
+		return Peer{}, err
+	}
+	request := &cli2.GetLeaderRequest2{
+		LogicPoolId: &logicalPoolId,
+		CopysetId:   &copysetId,
+		Peer:        peer.toProto(),
+	}
+	response, err := callCli2(ctx, cli, rpcCtx, cli2.CliService2Client.GetLeader, request)
+	if err != nil {
+		return Peer{}, err
+	}
+	return getPeer(response.GetLeader()), nil
+}
+
+// add peer to copyset through its leader, the old and new peers of copyset are returned
+func (cli *ChunkserverClient) AddPeer(leader Peer, logicalPoolId, copysetId uint32, peer Peer) ([]Peer, []Peer, error) {
+	return cli.AddPeerWithContext(context.Background(), leader, logicalPoolId, copysetId, peer)
+}
+
+func (cli *ChunkserverClient) AddPeerWithContext(ctx context.Context, leader Peer, logicalPoolId, copysetId uint32,
+	peer Peer) ([]Peer, []Peer, error) {
+	rpcCtx, err := peerRpcContext(leader, ADD_PEER, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	request := &cli2.AddPeerRequest2{
+		LogicPoolId: &logicalPoolId,
+		CopysetId:   &copysetId,
+		Leader:      leader.toProto(),
+		AddPeer:     peer.toProto(),
+	}
+	response, err := callCli2(ctx, cli, rpcCtx, cli2.CliService2Client.AddPeer, request)
+	if err != nil {
+		return nil, nil, err
+	}
+	return getPeers(response.GetOldPeers()), getPeers(response.GetNewPeers()), nil
+}
+
+// remove peer from copyset through its leader, the old and new peers of copyset are returned
+func (cli *ChunkserverClient) RemovePeer(leader Peer, logicalPoolId, copysetId uint32, peer Peer) ([]Peer, []Peer, error) {
+	return cli.RemovePeerWithContext(context.Background(), leader, logicalPoolId, copysetId, peer)
+}
+
+func (cli *ChunkserverClient) RemovePeerWithContext(ctx context.Context, leader Peer, logicalPoolId, copysetId uint32,
+	peer Peer) ([]Peer, []Peer, error) {
+	rpcCtx, err := peerRpcContext(leader, REMOVE_PEER, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	request := &cli2.RemovePeerRequest2{
+		LogicPoolId: &logicalPoolId,
+		CopysetId:   &copysetId,
+		Leader:      leader.toProto(),
+		RemovePeer:  peer.toProto(),
+	}
+	response, err := callCli2(ctx, cli, rpcCtx, cli2.CliService2Client.RemovePeer, request)
+	if err != nil {
+		return nil, nil, err
+	}
+	return getPeers(response.GetOldPeers()), getPeers(response.GetNewPeers()), nil
+}
+
+// replace the members of copyset with peers through its leader, the old and new peers of copyset are returned
+func (cli *ChunkserverClient) ChangePeers(leader Peer, logicalPoolId, copysetId uint32, peers []Peer) ([]Peer, []Peer, error) {
+	return cli.ChangePeersWithContext(context.Background(), leader, logicalPoolId, copysetId, peers)
+}
+
+func (cli *ChunkserverClient) ChangePeersWithContext(ctx context.Context, leader Peer, logicalPoolId, copysetId uint32,
+	peers []Peer) ([]Peer, []Peer, error) {
+	rpcCtx, err := peerRpcContext(leader, CHANGE_PEERS, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(peers) == 0 {
+		return nil, nil, fmt.Errorf("%s: %w: empty peers", CHANGE_PEERS, rpcerr.ErrInvalidParam)
+	}
+	request := &cli2.ChangePeersRequest2{
+		LogicPoolId: &logicalPoolId,
+		CopysetId:   &copysetId,
+		Leader:      leader.toProto(),
+		NewPeers:    toProtoPeers(peers),
+	}
+	response, err := callCli2(ctx, cli, rpcCtx, cli2.CliService2Client.ChangePeers, request)
+	if err != nil {
+		return nil, nil, err
+	}
+	return getPeers(response.GetOldPeers()), getPeers(response.GetNewPeers()), nil
+}
+
+// transfer the leadership of copyset to transferee
+func (cli *ChunkserverClient) TransferLeader(leader Peer, logicalPoolId, copysetId uint32, transferee Peer) error {
+	return cli.TransferLeaderWithContext(context.Background(), leader, logicalPoolId, copysetId, transferee)
+}
+
+func (cli *ChunkserverClient) TransferLeaderWithContext(ctx context.Context, leader Peer, logicalPoolId, copysetId uint32,
+	transferee Peer) error {
+	rpcCtx, err := peerRpcContext(leader, TRANSFER_LEADER, false)
+	if err != nil {
+		return err
+	}
+	request := &cli2.TransferLeaderRequest2{
+		LogicPoolId: &logicalPoolId,
+		CopysetId:   &copysetId,
+		Leader:      leader.toProto(),
+		Transferee:  transferee.toProto(),
+	}
+	_, err = callCli2(ctx, cli, rpcCtx, cli2.CliService2Client.TransferLeader, request)
+	return err
+}
+
+// force the configuration of copyset on peer to peers, only for recovering
+// a copyset which has lost the majority, data may be lost.
+func (cli *ChunkserverClient) ResetPeer(peer Peer, logicalPoolId, copysetId uint32, peers []Peer) error {
+	return cli.ResetPeerWithContext(context.Background(), peer, logicalPoolId, copysetId, peers)
+}
+
+func (cli *ChunkserverClient) ResetPeerWithContext(ctx context.Context, peer Peer, logicalPoolId, copysetId uint32,
+	peers []Peer) error {
+	rpcCtx, err := peerRpcContext(peer, RESET_PEER, false)
+	if err != nil {
+		return err
+	}
+	if len(peers) == 0 {
+		return fmt.Errorf("%s: %w: empty peers", RESET_PEER, rpcerr.ErrInvalidParam)
+	}
+	request := &cli2.ResetPeerRequest2{
+		LogicPoolId: &logicalPoolId,
+		CopysetId:   &copysetId,
+		RequestPeer: peer.toProto(),
+		NewPeers:    toProtoPeers(peers),
+	}
+	_, err = callCli2(ctx, cli, rpcCtx, cli2.CliService2Client.ResetPeer, request)
+	return err
+}
+
+// trigger a raft snapshot of copyset on peer
+func (cli *ChunkserverClient) Snapshot(peer Peer, logicalPoolId, copysetId uint32) error {
+	return cli.SnapshotWithContext(context.Background(), peer, logicalPoolId, copysetId)
+}
+
+func (cli *ChunkserverClient) SnapshotWithContext(ctx context.Context, peer Peer, logicalPoolId, copysetId uint32) error {
+	rpcCtx, err := peerRpcContext(peer, SNAPSHOT, true)
+	if err != nil {
+		return err
+	}
+	request := &cli2.SnapshotRequest2{
+		LogicPoolId: &logicalPoolId,
+		CopysetId:   &copysetId,
+		Peer:        peer.toProto(),
+	}
+	_, err = callCli2(ctx, cli, rpcCtx, cli2.CliService2Client.Snapshot, request)
+	return err
+}
+
+// trigger raft snapshots of all copysets on the chunkserver of peer
+func (cli *ChunkserverClient) SnapshotAll(peer Peer) error {
+	return cli.SnapshotAllWithContext(context.Background(), peer)
+}
+
+func (cli *ChunkserverClient) SnapshotAllWithContext(ctx context.Context, peer Peer) error {
+	rpcCtx, err := peerRpcContext(peer, SNAPSHOT_ALL, true)
+	if err != nil {
+		return err
+	}
+	_, err = callCli2(ctx, cli, rpcCtx, cli2.CliService2Client.SnapshotAll, &cli2.SnapshotAllRequest{})
+	return err
+}
@@ -0,0 +1,173 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/cli2"
+	pbcommon "github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/common"
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	csPort = ":12902"
+)
+
+var (
+	csGs *grpc.Server
+	cs   = &cliServer{leader: leader_peer}
+
+	leader_peer = Peer{Id: 1, ChunkServerId: 1, Address: "127.0.0.1:12902:0"}
+	member_peer = Peer{Address: "127.0.0.2:8200:0"}
+	new_peer    = Peer{Address: "127.0.0.3:8200:0"}
+
+	csClientOption ChunkserverClientOption = ChunkserverClientOption{
+		TimeoutMs:  500,
+		RetryTimes: 3,
+	}
+)
+
+// copysets of leader_peer and member_peer, whose leader is leader in the view of server
+type cliServer struct {
+	cli2.UnimplementedCliService2Server
+	leader     Peer
+	mu         sync.Mutex
+	transferee string
+	snapshots  int
+}
+
+func (s *cliServer) members() []*pbcommon.Peer {
+	return []*pbcommon.Peer{leader_peer.toProto(), member_peer.toProto()}
+}
+
+func (s *cliServer) GetLeader(ctx context.Context, req *cli2.GetLeaderRequest2) (*cli2.GetLeaderResponse2, error) {
+	switch req.GetCopysetId() {
+	case copyset_id, unavail_copyset_id, noleader_copyset_id, leaderless_copyset_id:
+	default:
+		return nil, status.Errorf(codes.NotFound, "copyset %d not found", req.GetCopysetId())
+	}
+	return &cli2.GetLeaderResponse2{Leader: s.leader.toProto()}, nil
+}
+
+func (s *cliServer) AddPeer(ctx context.Context, req *cli2.AddPeerRequest2) (*cli2.AddPeerResponse2, error) {
+	if req.GetLeader().GetAddress() != leader_peer.Address {
+		return nil, fmt.Errorf("%s is not leader", req.GetLeader().GetAddress())
+	}
+	return &cli2.AddPeerResponse2{
+		OldPeers: s.members(),
+		NewPeers: append(s.members(), req.GetAddPeer()),
+	}, nil
+}
+
+func (s *cliServer) TransferLeader(ctx context.Context, req *cli2.TransferLeaderRequest2) (
+	*cli2.TransferLeaderResponse2, error) {
+	s.mu.Lock()
+	s.transferee = req.GetTransferee().GetAddress()
+	s.mu.Unlock()
+	return &cli2.TransferLeaderResponse2{}, nil
+}
+
+func (s *cliServer) SnapshotAll(ctx context.Context, req *cli2.SnapshotAllRequest) (*cli2.SnapshotAllResponse, error) {
+	s.mu.Lock()
+	s.snapshots++
+	s.mu.Unlock()
+	return &cli2.SnapshotAllResponse{}, nil
+}
+
+func init() {
+	csGs = grpc.NewServer()
+	cli2.RegisterCliService2Server(csGs, cs)
+	go func() {
+		lis, err := net.Listen("tcp", csPort)
+		if err != nil {
+			fmt.Printf("failed to listen: %v", err)
+		}
+		if err := csGs.Serve(lis); err != nil {
+			fmt.Printf("failed to serve: %v", err)
+		}
+	}()
+}
+
+func TestPeerEndpoint(t *testing.T) {
+	peer := NewPeer("127.0.0.1", 8200)
+	if peer.Address != "127.0.0.1:8200:0" {
+		t.Errorf("TestPeerEndpoint expected address = 127.0.0.1:8200:0, actual address = %s", peer.Address)
+	}
+	if addr, err := peer.Endpoint(); err != nil || addr != "127.0.0.1:8200" {
+		t.Errorf("TestPeerEndpoint expected endpoint = 127.0.0.1:8200, actual = %s, error = %v", addr, err)
+	}
+	if _, err := (Peer{Address: "127.0.0.1"}).Endpoint(); !errors.Is(err, rpcerr.ErrInvalidParam) {
+		t.Errorf("TestPeerEndpoint expected ErrInvalidParam, actual error = %v", err)
+	}
+}
+
+func TestChunkserverClient(t *testing.T) {
+	client := NewChunkserverClient(csClientOption)
+	defer client.Close()
+
+	leader, err := client.GetLeader(leader_peer, logical_pool_id, copyset_id)
+	if err != nil {
+		t.Fatalf("TestChunkserverClient get leader rpc failed, error = %v", err)
+	}
+	if !reflect.DeepEqual(leader, leader_peer) {
+		t.Errorf("TestChunkserverClient expected leader = %+v, actual leader = %+v", leader_peer, leader)
+	}
+	if _, err := client.GetLeader(leader_peer, logical_pool_id, copyset_id+100); err == nil {
+		t.Errorf("TestChunkserverClient expected error of unknown copyset")
+	}
+
+	oldPeers, newPeers, err := client.AddPeer(leader, logical_pool_id, copyset_id, new_peer)
+	if err != nil {
+		t.Fatalf("TestChunkserverClient add peer rpc failed, error = %v", err)
+	}
+	if len(oldPeers) != 2 || len(newPeers) != 3 || newPeers[2].Address != new_peer.Address {
+		t.Errorf("TestChunkserverClient add peer response failed, old = %+v, new = %+v", oldPeers, newPeers)
+	}
+
+	if err := client.TransferLeader(leader, logical_pool_id, copyset_id, member_peer); err != nil {
+		t.Errorf("TestChunkserverClient transfer leader rpc failed, error = %v", err)
+	}
+	if err := client.SnapshotAll(leader); err != nil {
+		t.Errorf("TestChunkserverClient snapshot all rpc failed, error = %v", err)
+	}
+	cs.mu.Lock()
+	if cs.transferee != member_peer.Address || cs.snapshots != 1 {
+		t.Errorf("TestChunkserverClient expected transferee = %s and 1 snapshot, actual = %s, %d",
+			member_peer.Address, cs.transferee, cs.snapshots)
+	}
+	cs.mu.Unlock()
+
+	if _, _, err := client.ChangePeers(leader, logical_pool_id, copyset_id, nil); !errors.Is(err, rpcerr.ErrInvalidParam) {
+		t.Errorf("TestChunkserverClient expected ErrInvalidParam, actual error = %v", err)
+	}
+}
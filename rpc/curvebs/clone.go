@@ -0,0 +1,187 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/nameserver2"
+	"github.com/SeanHai/curve-go-rpc/rpc/baserpc"
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
+)
+
+const (
+	// interval to poll the status of clone file
+	CLONE_STATUS_POLL_INTERVAL = 500 * time.Millisecond
+
+	// apis
+	CREATE_CLONE_FILE     = "CreateCloneFile"
+	SET_CLONE_FILE_STATUS = "SetCloneFileStatus"
+)
+
+// order of the status of a clone file
+var cloneStatusOrder = map[string]int{
+	FILE_CLONING:             1,
+	FILE_CLONEMETA_INSTALLED: 2,
+	FILE_CLONED:              3,
+}
+
+func parseFileStatus(s string) (nameserver2.FileStatus, error) {
+	switch s {
+	case FILE_CREATED:
+		return nameserver2.FileStatus_kFileCreated, nil
+	case FILE_DELETING:
+		return nameserver2.FileStatus_kFileDeleting, nil
+	case FILE_CLONING:
+		return nameserver2.FileStatus_kFileCloning, nil
+	case FILE_CLONEMETA_INSTALLED:
+		return nameserver2.FileStatus_kFileCloneMetaInstalled, nil
+	case FILE_CLONED:
+		return nameserver2.FileStatus_kFileCloned, nil
+	case FILE_BEIING_CLONED:
+		return nameserver2.FileStatus_kFileBeingCloned, nil
+	default:
+		return 0, fmt.Errorf("%w: file status %s", rpcerr.ErrInvalidParam, s)
+	}
+}
+
+// create a page file cloned lazily from cloneSource, whose chunks are read
+// from cloneSource until they are written. The file is in FILE_CLONING status.
+func (cli *MdsClient) CreateCloneFile(filename, owner, sig, cloneSource string, length, seq uint64, chunkSize uint32,
+	date uint64) (FileInfo, error) {
+	return cli.CreateCloneFileWithContext(context.Background(), filename, owner, sig, cloneSource, length, seq,
+		chunkSize, date)
+}
+
+func (cli *MdsClient) CreateCloneFileWithContext(ctx context.Context, filename, owner, sig, cloneSource string,
+	length, seq uint64, chunkSize uint32, date uint64) (FileInfo, error) {
+	fileType := nameserver2.FileType_INODE_PAGEFILE
+	request := &nameserver2.CreateCloneFileRequest{
+		FileName:   &filename,
+		FileType:   &fileType,
+		FileLength: &length,
+		Seq:        &seq,
+		ChunkSize:  &chunkSize,
+		Owner:      &owner,
+		Date:       &date,
+	}
+	if sig != "" {
+		request.Signature = &sig
+	}
+	if cloneSource != "" {
+		request.CloneSource = &cloneSource
+	}
+	response, err := callNameServer(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, CREATE_CLONE_FILE),
+		nameserver2.CurveFSServiceClient.CreateCloneFile, request)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return getFileInfo(response.GetFileInfo()), nil
+}
+
+// set status of clone file, status is one of FILE_CLONING/FILE_CLONEMETA_INSTALLED/FILE_CLONED/...
+// fileId is checked by mds if it is not 0.
+func (cli *MdsClient) SetCloneFileStatus(filename, owner, sig string, fileId uint64, status string, date uint64) error {
+	return cli.SetCloneFileStatusWithContext(context.Background(), filename, owner, sig, fileId, status, date)
+}
+
+func (cli *MdsClient) SetCloneFileStatusWithContext(ctx context.Context, filename, owner, sig string, fileId uint64,
+	status string, date uint64) error {
+	fileStatus, err := parseFileStatus(status)
+	if err != nil {
+		return err
+	}
+	request := &nameserver2.SetCloneFileStatusRequest{
+		FileName:   &filename,
+		FileStatus: &fileStatus,
+		Owner:      &owner,
+		Date:       &date,
+	}
+	if sig != "" {
+		request.Signature = &sig
+	}
+	if fileId != 0 {
+		request.FileID = &fileId
+	}
+	_, err = callNameServer(ctx, cli, baserpc.NewRpcContext(cli.addrs, SET_CLONE_FILE_STATUS),
+		nameserver2.CurveFSServiceClient.SetCloneFileStatus, request)
+	return err
+}
+
+// WaitCloneFileStatus polls the file until it reaches status or a later clone
+// status, e.g. FILE_CLONED is regarded as reaching FILE_CLONEMETA_INSTALLED.
+// It gives up after timeout, 0 means no timeout. Every poll is signed by
+// credential again, so the wait may outlast the expiration of signature.
+func (cli *MdsClient) WaitCloneFileStatus(filename string, credential Credential, status string,
+	timeout time.Duration) (FileInfo, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return cli.WaitCloneFileStatusWithContext(ctx, filename, credential, status)
+}
+
+func (cli *MdsClient) WaitCloneFileStatusWithContext(ctx context.Context, filename string, credential Credential,
+	status string) (FileInfo, error) {
+	return waitCloneFileStatus(ctx, filename, status, func(ctx context.Context) (FileInfo, error) {
+		sig, date := credential.Sign()
+		return cli.GetFileInfoWithContext(ctx, filename, credential.Owner, sig, date)
+	})
+}
+
+func waitCloneFileStatus(ctx context.Context, filename, status string,
+	getFileInfo func(ctx context.Context) (FileInfo, error)) (FileInfo, error) {
+	if _, err := parseFileStatus(status); err != nil {
+		return FileInfo{}, err
+	}
+	ticker := time.NewTicker(CLONE_STATUS_POLL_INTERVAL)
+	defer ticker.Stop()
+	for {
+		info, err := getFileInfo(ctx)
+		if err != nil {
+			return info, err
+		}
+		if reachCloneStatus(info.FileStatus, status) {
+			return info, nil
+		}
+		select {
+		case <-ctx.Done():
+			return info, fmt.Errorf("wait %s to be %s, current status %s: %w", filename, status, info.FileStatus,
+				ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func reachCloneStatus(current, target string) bool {
+	if current == target {
+		return true
+	}
+	cur, ok1 := cloneStatusOrder[current]
+	tgt, ok2 := cloneStatusOrder[target]
+	return ok1 && ok2 && cur >= tgt
+}
@@ -0,0 +1,203 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"context"
+	"sync"
+
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/copyset"
+)
+
+const (
+	// raft state of copyset
+	STATE_LEADER        = "STATE_LEADER"
+	STATE_TRANSFERRING  = "STATE_TRANSFERRING"
+	STATE_CANDIDATE     = "STATE_CANDIDATE"
+	STATE_FOLLOWER      = "STATE_FOLLOWER"
+	STATE_ERROR         = "STATE_ERROR"
+	STATE_UNINITIALIZED = "STATE_UNINITIALIZED"
+	STATE_SHUTTING      = "STATE_SHUTTING"
+	STATE_SHUTDOWN      = "STATE_SHUTDOWN"
+
+	DEFAULT_COPYSET_STATUS_CONCURRENCY = 8
+
+	// apis
+	GET_COPYSET_STATUS = "GetCopysetStatus"
+)
+
+// braft::State
+var raftStates = map[int32]string{
+	1: STATE_LEADER,
+	2: STATE_TRANSFERRING,
+	3: STATE_CANDIDATE,
+	4: STATE_FOLLOWER,
+	5: STATE_ERROR,
+	6: STATE_UNINITIALIZED,
+	7: STATE_SHUTTING,
+	8: STATE_SHUTDOWN,
+}
+
+// CopysetStatus is the raft status of a replica of copyset, the chunkserver
+// only knows itself and the leader among the peers.
+type CopysetStatus struct {
+	LogicalPoolId     uint32 `json:"logicalPoolId"`
+	CopysetId         uint32 `json:"copysetId"`
+	State             string `json:"state"`
+	Peer              Peer   `json:"peer"`
+	Leader            Peer   `json:"leader"`
+	ReadOnly          bool   `json:"readOnly"`
+	Term              int64  `json:"term"`
+	CommittedIndex    int64  `json:"committedIndex"`
+	KnownAppliedIndex int64  `json:"knownAppliedIndex"`
+	PendingIndex      int64  `json:"pendingIndex"`
+	PendingQueueSize  int64  `json:"pendingQueueSize"`
+	ApplyingIndex     int64  `json:"applyingIndex"`
+	FirstIndex        int64  `json:"firstIndex"`
+	LastIndex         int64  `json:"lastIndex"`
+	DiskIndex         int64  `json:"diskIndex"`
+	Epoch             uint64 `json:"epoch"`
+	// the replica is a writable leader or follower which knows its leader
+	Healthy bool `json:"healthy"`
+}
+
+// CopysetStatusError is why the status of a copyset is not got
+type CopysetStatusError struct {
+	LogicalPoolId uint32 `json:"logicalPoolId"`
+	CopysetId     uint32 `json:"copysetId"`
+	Err           error  `json:"-"`
+}
+
+func getCopysetStatus(logicalPoolId, copysetId uint32, response *copyset.CopysetStatusResponse) CopysetStatus {
+	status := CopysetStatus{
+		LogicalPoolId:     logicalPoolId,
+		CopysetId:         copysetId,
+		State:             raftStates[response.GetState()],
+		Peer:              getPeer(response.GetPeer()),
+		Leader:            getPeer(response.GetLeader()),
+		ReadOnly:          response.GetReadOnly(),
+		Term:              response.GetTerm(),
+		CommittedIndex:    response.GetCommittedIndex(),
+		KnownAppliedIndex: response.GetKnownAppliedIndex(),
+		PendingIndex:      response.GetPendingIndex(),
+		PendingQueueSize:  response.GetPendingQueueSize(),
+		ApplyingIndex:     response.GetApplyingIndex(),
+		FirstIndex:        response.GetFirstIndex(),
+		LastIndex:         response.GetLastIndex(),
+		DiskIndex:         response.GetDiskIndex(),
+		Epoch:             response.GetEpoch(),
+	}
+	if status.State == "" {
+		status.State = INVALID
+	}
+	_, hasLeader := leaderEndpoint(status.Leader)
+	status.Healthy = (status.State == STATE_LEADER || status.State == STATE_FOLLOWER) && !status.ReadOnly && hasLeader
+	return status
+}
+
+// leaderEndpoint returns ip:port of leader, braft reports no leader as 0.0.0.0:0:0
+func leaderEndpoint(leader Peer) (string, bool) {
+	addr, err := leader.Endpoint()
+	if err != nil || addr == "0.0.0.0:0" {
+		return "", false
+	}
+	return addr, true
+}
+
+// get the raft status of copyset on peer,
+// rpcerr.ErrCopySetNotFound is returned if there is no such replica on peer.
+func (cli *ChunkserverClient) GetCopysetStatus(peer Peer, logicalPoolId, copysetId uint32) (CopysetStatus, error) {
+	return cli.GetCopysetStatusWithContext(context.Background(), peer, logicalPoolId, copysetId)
+}
+
+func (cli *ChunkserverClient) GetCopysetStatusWithContext(ctx context.Context, peer Peer, logicalPoolId,
+	copysetId uint32) (CopysetStatus, error) {
+	rpcCtx, err := peerRpcContext(peer, GET_COPYSET_STATUS, true)
+	if err != nil {
+		return CopysetStatus{}, err
+	}
+	queryHash := false
+	request := &copyset.CopysetStatusRequest{
+		LogicPoolId: &logicalPoolId,
+		CopysetId:   &copysetId,
+		Peer:        peer.toProto(),
+		QueryHash:   &queryHash,
+	}
+	response, err := callCopyset(ctx, cli, rpcCtx, copyset.CopysetServiceClient.GetCopysetStatus, request)
+	if err != nil {
+		return CopysetStatus{}, err
+	}
+	return getCopysetStatus(logicalPoolId, copysetId, response), nil
+}
+
+// get the status of all copysets on chunkserver ip:port listed by mds, at most
+// concurrency rpcs are in flight, DEFAULT_COPYSET_STATUS_CONCURRENCY if not set.
+// Failures of copysets are returned apart, only failures of mds fail the call.
+func (cli *ChunkserverClient) GetChunkServerCopysetStatus(mds *MdsClient, ip string, port uint32,
+	concurrency int) ([]CopysetStatus, []CopysetStatusError, error) {
+	return cli.GetChunkServerCopysetStatusWithContext(context.Background(), mds, ip, port, concurrency)
+}
+
+func (cli *ChunkserverClient) GetChunkServerCopysetStatusWithContext(ctx context.Context, mds *MdsClient,
+	ip string, port uint32, concurrency int) ([]CopysetStatus, []CopysetStatusError, error) {
+	copysets, err := mds.GetCopySetsInChunkServerWithContext(ctx, ip, port)
+	if err != nil {
+		return nil, nil, err
+	}
+	if concurrency <= 0 {
+		concurrency = DEFAULT_COPYSET_STATUS_CONCURRENCY
+	}
+
+	peer := NewPeer(ip, port)
+	statuses := make([]CopysetStatus, len(copysets))
+	errs := make([]error, len(copysets))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, cs := range copysets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, cs CopySetInfo) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			statuses[i], errs[i] = cli.GetCopysetStatusWithContext(ctx, peer, cs.LogicalPoolId, cs.CopysetId)
+		}(i, cs)
+	}
+	wg.Wait()
+
+	infos := []CopysetStatus{}
+	failures := []CopysetStatusError{}
+	for i, cs := range copysets {
+		if errs[i] != nil {
+			failures = append(failures, CopysetStatusError{
+				LogicalPoolId: cs.LogicalPoolId,
+				CopysetId:     cs.CopysetId,
+				Err:           errs[i],
+			})
+			continue
+		}
+		infos = append(infos, statuses[i])
+	}
+	return infos, failures, nil
+}
@@ -0,0 +1,254 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+
+	pbcommon "github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/common"
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/copyset"
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/topology"
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
+	"google.golang.org/grpc"
+)
+
+const (
+	copysetPort = ":12905"
+)
+
+var (
+	copysetGs *grpc.Server
+
+	copyset_peer                   = NewPeer("127.0.0.1", 12905)
+	missing_copyset_id      uint32 = 100
+	copyset_term            int64  = 3
+	copyset_committed_index int64  = 1024
+	copysetClientOption            = MdsClientOption{
+		TimeoutMs:  500,
+		RetryTimes: 1,
+		Addrs:      []string{"127.0.0.1:12905"},
+	}
+)
+
+// the chunkserver is the leader of copyset_id, and a follower without
+// leader of unavail_copyset_id
+type copysetServer struct {
+	copyset.UnimplementedCopysetServiceServer
+}
+
+func (s *copysetServer) GetCopysetStatus(ctx context.Context, req *copyset.CopysetStatusRequest) (
+	*copyset.CopysetStatusResponse, error) {
+	var state int32
+	leader := "0.0.0.0:0:0"
+	switch req.GetCopysetId() {
+	case copyset_id:
+		state, leader = 1, copyset_peer.Address
+	case unavail_copyset_id:
+		state = 4
+	default:
+		return &copyset.CopysetStatusResponse{
+			Status: copyset.COPYSET_OP_STATUS_COPYSET_OP_STATUS_COPYSET_NOTEXIST.Enum(),
+		}, nil
+	}
+	return &copyset.CopysetStatusResponse{
+		Status:         copyset.COPYSET_OP_STATUS_COPYSET_OP_STATUS_SUCCESS.Enum(),
+		State:          &state,
+		Peer:           req.GetPeer(),
+		Leader:         &pbcommon.Peer{Address: &leader},
+		Term:           &copyset_term,
+		CommittedIndex: &copyset_committed_index,
+	}, nil
+}
+
+type copysetTopoServer struct {
+	topology.UnimplementedTopologyServiceServer
+}
+
+func (s *copysetTopoServer) GetCopySetsInChunkServer(ctx context.Context, req *topology.GetCopySetsInChunkServerRequest) (
+	*topology.GetCopySetsInChunkServerResponse, error) {
+	response := &topology.GetCopySetsInChunkServerResponse{StatusCode: &status_success}
+	for _, id := range []uint32{copyset_id, unavail_copyset_id, missing_copyset_id} {
+		copysetId := id
+		response.CopysetInfos = append(response.CopysetInfos, &pbcommon.CopysetInfo{
+			LogicalPoolId: &logical_pool_id,
+			CopysetId:     &copysetId,
+		})
+	}
+	return response, nil
+}
+
+func (s *copysetTopoServer) GetCopySetsInCluster(ctx context.Context, req *topology.GetCopySetsInClusterRequest) (
+	*topology.GetCopySetsInClusterResponse, error) {
+	chunkServers, err := s.GetCopySetsInChunkServer(ctx, &topology.GetCopySetsInChunkServerRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return &topology.GetCopySetsInClusterResponse{
+		StatusCode:   &status_success,
+		CopysetInfos: chunkServers.GetCopysetInfos(),
+	}, nil
+}
+
+// all copysets are on this chunkserver, and missing_copyset_id is also on the down one
+func (s *copysetTopoServer) GetChunkServerListInCopySets(ctx context.Context, req *topology.GetChunkServerListInCopySetsRequest) (
+	*topology.GetChunkServerListInCopySetsResponse, error) {
+	response := &topology.GetChunkServerListInCopySetsResponse{StatusCode: &status_success}
+	ports := []uint32{12905, down_replica_port}
+	for i, id := range req.GetCopysetId() {
+		info := &topology.CopySetServerInfo{CopysetId: &req.CopysetId[i]}
+		for j := range ports {
+			if j > 0 && id != missing_copyset_id {
+				break
+			}
+			info.CsLocs = append(info.CsLocs, &pbcommon.ChunkServerLocation{
+				ChunkServerID: &ports[j],
+				HostIp:        &replica_ip,
+				Port:          &ports[j],
+			})
+		}
+		response.CsInfo = append(response.CsInfo, info)
+	}
+	return response, nil
+}
+
+func init() {
+	copysetGs = grpc.NewServer()
+	copyset.RegisterCopysetServiceServer(copysetGs, &copysetServer{})
+	topology.RegisterTopologyServiceServer(copysetGs, &copysetTopoServer{})
+	go func() {
+		lis, err := net.Listen("tcp", copysetPort)
+		if err != nil {
+			fmt.Printf("failed to listen: %v", err)
+		}
+		if err := copysetGs.Serve(lis); err != nil {
+			fmt.Printf("failed to serve: %v", err)
+		}
+	}()
+}
+
+func TestGetCopysetStatus(t *testing.T) {
+	client := NewChunkserverClient(csClientOption)
+	defer client.Close()
+
+	status, err := client.GetCopysetStatus(copyset_peer, logical_pool_id, copyset_id)
+	if err != nil {
+		t.Fatalf("TestGetCopysetStatus rpc failed, error = %v", err)
+	}
+	if status.State != STATE_LEADER || !status.Healthy || status.Term != copyset_term ||
+		status.CommittedIndex != copyset_committed_index {
+		t.Errorf("TestGetCopysetStatus response failed, actual status = %+v", status)
+	}
+	status, err = client.GetCopysetStatus(copyset_peer, logical_pool_id, unavail_copyset_id)
+	if err != nil {
+		t.Fatalf("TestGetCopysetStatus rpc failed, error = %v", err)
+	}
+	if status.State != STATE_FOLLOWER || status.Healthy {
+		t.Errorf("TestGetCopysetStatus expected unhealthy follower, actual status = %+v", status)
+	}
+	_, err = client.GetCopysetStatus(copyset_peer, logical_pool_id, missing_copyset_id)
+	if !errors.Is(err, rpcerr.ErrCopySetNotFound) {
+		t.Errorf("TestGetCopysetStatus expected ErrCopySetNotFound, actual error = %v", err)
+	}
+}
+
+func TestGetChunkServerCopysetStatus(t *testing.T) {
+	mdsClient := NewMdsClient(copysetClientOption)
+	defer mdsClient.Close()
+	client := NewChunkserverClient(csClientOption)
+	defer client.Close()
+
+	statuses, failures, err := client.GetChunkServerCopysetStatus(mdsClient, "127.0.0.1", 12905, 2)
+	if err != nil {
+		t.Fatalf("TestGetChunkServerCopysetStatus rpc failed, error = %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].CopysetId != copyset_id || statuses[1].CopysetId != unavail_copyset_id {
+		t.Errorf("TestGetChunkServerCopysetStatus response failed, actual statuses = %+v", statuses)
+	}
+	if len(failures) != 1 || failures[0].CopysetId != missing_copyset_id ||
+		!errors.Is(failures[0].Err, rpcerr.ErrCopySetNotFound) {
+		t.Errorf("TestGetChunkServerCopysetStatus expected failure of copyset %d, actual failures = %+v",
+			missing_copyset_id, failures)
+	}
+}
+
+func TestCheckCopysets(t *testing.T) {
+	mdsClient := NewMdsClient(copysetClientOption)
+	defer mdsClient.Close()
+	option := csClientOption
+	option.RetryTimes = 1
+	client := NewChunkserverClient(option)
+	defer client.Close()
+
+	report, err := client.CheckCopysets(mdsClient, CheckCopysetsOption{Concurrency: 2})
+	if err != nil {
+		t.Fatalf("TestCheckCopysets rpc failed, error = %v", err)
+	}
+	if report.Total != 3 || len(report.Pools) != 1 || report.Pools[0].Total != 3 {
+		t.Fatalf("TestCheckCopysets expected 3 copysets in 1 pool, actual report = %+v", report)
+	}
+	expected := map[string]int{
+		COPYSET_HEALTHY:          1,
+		COPYSET_LEADERLESS:       2,
+		COPYSET_UNDER_REPLICATED: 1,
+	}
+	if !reflect.DeepEqual(report.Counts, expected) {
+		t.Errorf("TestCheckCopysets expected counts = %v, actual counts = %v", expected, report.Counts)
+	}
+	missing := report.Copysets[2]
+	if missing.CopysetId != missing_copyset_id || len(missing.Failures) != 2 || len(missing.Statuses) != 0 {
+		t.Errorf("TestCheckCopysets expected 2 failures of copyset %d, actual check = %+v", missing_copyset_id, missing)
+	}
+}
+
+func TestClassifyCopyset(t *testing.T) {
+	replicas := []ChunkServerLocation{
+		{HostIp: "127.0.0.1", Port: 8200},
+		{HostIp: "127.0.0.2", Port: 8200},
+	}
+	leader := Peer{Address: "127.0.0.1:8200:0"}
+	check := &CopysetCheck{
+		Replicas: replicas,
+		Statuses: []CopysetStatus{
+			{State: STATE_LEADER, Leader: leader, KnownAppliedIndex: 5000},
+			{State: STATE_FOLLOWER, Leader: leader, KnownAppliedIndex: 100},
+		},
+	}
+	option := CheckCopysetsOption{MaxAppliedIndexGap: DEFAULT_APPLIED_INDEX_GAP}
+	if problems := classifyCopyset(check, option); !reflect.DeepEqual(problems, []string{COPYSET_LAGGING}) {
+		t.Errorf("TestClassifyCopyset expected lagging, actual problems = %v", problems)
+	}
+
+	option.ReplicaNum = 3
+	check.Statuses[1].KnownAppliedIndex = 5000
+	check.Statuses[0].Leader = Peer{Address: "127.0.0.3:8200:0"}
+	check.Statuses[1].Leader = check.Statuses[0].Leader
+	expected := []string{COPYSET_UNDER_REPLICATED, COPYSET_PEER_MISMATCH}
+	if problems := classifyCopyset(check, option); !reflect.DeepEqual(problems, expected) {
+		t.Errorf("TestClassifyCopyset expected %v, actual problems = %v", expected, problems)
+	}
+}
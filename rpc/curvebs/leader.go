@@ -0,0 +1,128 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
+)
+
+// LeaderView is the leader of copyset in the view of a replica
+type LeaderView struct {
+	Replica ChunkServerLocation `json:"replica"`
+	Leader  Peer                `json:"leader"`
+}
+
+// ReplicaError is why a replica failed to tell its leader
+type ReplicaError struct {
+	Replica ChunkServerLocation `json:"replica"`
+	Err     error               `json:"-"`
+}
+
+type CopysetLeader struct {
+	LogicalPoolId uint32 `json:"logicalPoolId"`
+	CopysetId     uint32 `json:"copysetId"`
+	// the leader all reachable replicas with a leader agree on,
+	// zero if they disagree or no leader is known
+	Leader   Peer                  `json:"leader"`
+	Replicas []ChunkServerLocation `json:"replicas"`
+	Views    []LeaderView          `json:"views"`
+	// reachable replicas report different leaders, replicas without leader are ignored
+	Disagreed bool `json:"disagreed"`
+	// all reachable replicas report no leader
+	NoLeader    bool           `json:"noLeader"`
+	Unreachable []ReplicaError `json:"unreachable"`
+}
+
+// find the replicas of copyset from mds, and ask all of them for the leader
+// concurrently. Failures of replicas are reported in Unreachable rather than
+// returned, only failures of mds are returned.
+func (cli *ChunkserverClient) GetCopysetLeader(mds *MdsClient, logicalPoolId, copysetId uint32) (CopysetLeader, error) {
+	return cli.GetCopysetLeaderWithContext(context.Background(), mds, logicalPoolId, copysetId)
+}
+
+func (cli *ChunkserverClient) GetCopysetLeaderWithContext(ctx context.Context, mds *MdsClient,
+	logicalPoolId, copysetId uint32) (CopysetLeader, error) {
+	infos, err := mds.GetChunkServerListInCopySetsWithContext(ctx, logicalPoolId, []uint32{copysetId})
+	if err != nil {
+		return CopysetLeader{}, err
+	}
+	var replicas []ChunkServerLocation
+	for _, info := range infos {
+		if info.CopysetId == copysetId {
+			replicas = info.CsLocs
+		}
+	}
+	if len(replicas) == 0 {
+		return CopysetLeader{}, fmt.Errorf("%s: %w: logical pool id: %d, copyset id: %d",
+			GET_LEADER, rpcerr.ErrCopySetNotFound, logicalPoolId, copysetId)
+	}
+
+	result := CopysetLeader{
+		LogicalPoolId: logicalPoolId,
+		CopysetId:     copysetId,
+		Replicas:      replicas,
+		Views:         []LeaderView{},
+		Unreachable:   []ReplicaError{},
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, replica := range replicas {
+		wg.Add(1)
+		go func(replica ChunkServerLocation) {
+			defer wg.Done()
+			leader, err := cli.GetLeaderWithContext(ctx, NewPeer(replica.HostIp, replica.Port), logicalPoolId, copysetId)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				result.Unreachable = append(result.Unreachable, ReplicaError{Replica: replica, Err: err})
+				return
+			}
+			result.Views = append(result.Views, LeaderView{Replica: replica, Leader: leader})
+		}(replica)
+	}
+	wg.Wait()
+
+	var leader *Peer
+	leaderAddr := ""
+	for i := range result.Views {
+		addr, ok := leaderEndpoint(result.Views[i].Leader)
+		if !ok {
+			continue
+		}
+		if leader == nil {
+			leader, leaderAddr = &result.Views[i].Leader, addr
+		} else if addr != leaderAddr {
+			result.Disagreed = true
+		}
+	}
+	if leader == nil {
+		result.NoLeader = len(result.Views) > 0
+	} else if !result.Disagreed {
+		result.Leader = *leader
+	}
+	return result, nil
+}
@@ -0,0 +1,168 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/cli2"
+	pbcommon "github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/common"
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/topology"
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
+	"google.golang.org/grpc"
+)
+
+const (
+	leaderPort   = ":12903"
+	noLeaderPort = ":12908"
+)
+
+var (
+	leaderGs   *grpc.Server
+	noLeaderGs *grpc.Server
+
+	// replicas of copyset_id are :12902 and :12904 which is down,
+	// replicas of unavail_copyset_id are :12902 and :12903 which disagree,
+	// replicas of noleader_copyset_id are :12902 and :12908 which has no leader,
+	// the only replica of leaderless_copyset_id is :12908.
+	replica_ip                   = "127.0.0.1"
+	replica_port          uint32 = 12902
+	other_replica_port    uint32 = 12903
+	down_replica_port     uint32 = 12904
+	noleader_replica_port uint32 = 12908
+	noleader_copyset_id   uint32 = 8
+	leaderless_copyset_id uint32 = 9
+	leaderClientOption           = MdsClientOption{
+		TimeoutMs:  500,
+		RetryTimes: 1,
+		Addrs:      []string{"127.0.0.1:12903"},
+	}
+)
+
+type replicaServer struct {
+	topology.UnimplementedTopologyServiceServer
+}
+
+func (s *replicaServer) GetChunkServerListInCopySets(ctx context.Context, req *topology.GetChunkServerListInCopySetsRequest) (
+	*topology.GetChunkServerListInCopySetsResponse, error) {
+	response := &topology.GetChunkServerListInCopySetsResponse{StatusCode: &status_success}
+	for i, id := range req.GetCopysetId() {
+		var ports []uint32
+		switch id {
+		case copyset_id:
+			ports = []uint32{replica_port, down_replica_port}
+		case unavail_copyset_id:
+			ports = []uint32{replica_port, other_replica_port}
+		case noleader_copyset_id:
+			ports = []uint32{replica_port, noleader_replica_port}
+		case leaderless_copyset_id:
+			ports = []uint32{noleader_replica_port}
+		}
+		if len(ports) == 0 {
+			continue
+		}
+		info := &topology.CopySetServerInfo{CopysetId: &req.CopysetId[i]}
+		for j := range ports {
+			info.CsLocs = append(info.CsLocs, &pbcommon.ChunkServerLocation{
+				ChunkServerID: &ports[j],
+				HostIp:        &replica_ip,
+				Port:          &ports[j],
+			})
+		}
+		response.CsInfo = append(response.CsInfo, info)
+	}
+	return response, nil
+}
+
+func init() {
+	leaderGs = grpc.NewServer()
+	cli2.RegisterCliService2Server(leaderGs, &cliServer{leader: member_peer})
+	topology.RegisterTopologyServiceServer(leaderGs, &replicaServer{})
+	noLeaderGs = grpc.NewServer()
+	// braft reports no leader as 0.0.0.0:0:0
+	cli2.RegisterCliService2Server(noLeaderGs, &cliServer{leader: Peer{Address: "0.0.0.0:0:0"}})
+	for port, gs := range map[string]*grpc.Server{leaderPort: leaderGs, noLeaderPort: noLeaderGs} {
+		go func(port string, gs *grpc.Server) {
+			lis, err := net.Listen("tcp", port)
+			if err != nil {
+				fmt.Printf("failed to listen: %v", err)
+			}
+			if err := gs.Serve(lis); err != nil {
+				fmt.Printf("failed to serve: %v", err)
+			}
+		}(port, gs)
+	}
+}
+
+func TestGetCopysetLeader(t *testing.T) {
+	mdsClient := NewMdsClient(leaderClientOption)
+	defer mdsClient.Close()
+	option := csClientOption
+	option.RetryTimes = 1
+	client := NewChunkserverClient(option)
+	defer client.Close()
+
+	result, err := client.GetCopysetLeader(mdsClient, logical_pool_id, copyset_id)
+	if err != nil {
+		t.Fatalf("TestGetCopysetLeader rpc failed, error = %v", err)
+	}
+	if result.Leader.Address != leader_peer.Address || result.Disagreed || len(result.Views) != 1 {
+		t.Errorf("TestGetCopysetLeader expected leader = %s, actual result = %+v", leader_peer.Address, result)
+	}
+	if len(result.Unreachable) != 1 || result.Unreachable[0].Replica.Port != down_replica_port {
+		t.Errorf("TestGetCopysetLeader expected unreachable replica %d, actual = %+v", down_replica_port, result.Unreachable)
+	}
+
+	result, err = client.GetCopysetLeader(mdsClient, logical_pool_id, unavail_copyset_id)
+	if err != nil {
+		t.Fatalf("TestGetCopysetLeader disagreed rpc failed, error = %v", err)
+	}
+	if !result.Disagreed || result.Leader.Address != "" || len(result.Views) != 2 || len(result.Unreachable) != 0 {
+		t.Errorf("TestGetCopysetLeader expected disagreement, actual result = %+v", result)
+	}
+
+	result, err = client.GetCopysetLeader(mdsClient, logical_pool_id, noleader_copyset_id)
+	if err != nil {
+		t.Fatalf("TestGetCopysetLeader no leader replica rpc failed, error = %v", err)
+	}
+	if result.Leader.Address != leader_peer.Address || result.Disagreed || result.NoLeader || len(result.Views) != 2 {
+		t.Errorf("TestGetCopysetLeader expected replica without leader ignored, actual result = %+v", result)
+	}
+
+	result, err = client.GetCopysetLeader(mdsClient, logical_pool_id, leaderless_copyset_id)
+	if err != nil {
+		t.Fatalf("TestGetCopysetLeader leaderless rpc failed, error = %v", err)
+	}
+	if !result.NoLeader || result.Disagreed || result.Leader != (Peer{}) || len(result.Views) != 1 {
+		t.Errorf("TestGetCopysetLeader expected no leader, actual result = %+v", result)
+	}
+
+	_, err = client.GetCopysetLeader(mdsClient, logical_pool_id, copyset_id+100)
+	if !errors.Is(err, rpcerr.ErrCopySetNotFound) {
+		t.Errorf("TestGetCopysetLeader expected ErrCopySetNotFound, actual error = %v", err)
+	}
+}
@@ -23,28 +23,180 @@
 package curvebs
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"time"
 
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/nameserver2"
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/topology/statuscode"
 	"github.com/SeanHai/curve-go-rpc/rpc/baserpc"
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
 )
 
 type MdsClientOption struct {
 	TimeoutMs  int
 	RetryTimes uint32
 	Addrs      []string
+	// idle connections are closed after ConnIdleTimeoutMs, the client creates its own pool
+	// if it is set, 0 means baserpc.DEFAULT_CONN_IDLE_TIMEOUT
+	ConnIdleTimeoutMs int
+	// share connections with other clients, baserpc.DefaultConnPool() is used if nil
+	// and ConnIdleTimeoutMs is not set
+	ConnPool *baserpc.ConnPool
+	// retry backoff, 0 means baserpc.DEFAULT_RETRY_BACKOFF and baserpc.DEFAULT_RETRY_MAX_BACKOFF
+	RetryBackoffMs    int
+	RetryMaxBackoffMs int
+	// overall deadline of an rpc including retries, 0 means no limit besides RetryTimes
+	RetryDeadlineMs int
+	// also retry rpcs which are not idempotent, such as CreateFile and DeleteFile
+	RetryNonIdempotent bool
+	// refuse mutating rpcs if the cluster id of mds is not ExpectedClusterId, empty means no check
+	ExpectedClusterId string
+	// credential used by MdsClient.Auth to sign namespace rpcs
+	Owner    string
+	Password string
 }
 
 type MdsClient struct {
 	addrs      []string
 	baseClient *baserpc.BaseRpc
+	ownPool    bool
+
+	credential        Credential
+	expectedClusterId string
+	clusterMu         sync.Mutex
+	clusterVerified   bool
+}
+
+// rpcs which modify the cluster, checked against MdsClientOption.ExpectedClusterId
+var mutatingRpcs = map[string]bool{
+	CREATE_PHYSICAL_POOL:        true,
+	DELETE_PHYSICAL_POOL:        true,
+	CREATE_ZONE:                 true,
+	DELETE_ZONE:                 true,
+	REGIST_SERVER:               true,
+	DELETE_SERVER:               true,
+	CREATE_LOGICAL_POOL:         true,
+	DELETE_LOGICAL_POOL:         true,
+	SET_LOGICAL_POOL:            true,
+	SET_LOGICAL_POOL_SCAN_STATE: true,
+	REGIST_CHUNKSERVER:          true,
+	SET_CHUNKSERVER:             true,
+	DELETE_CHUNKSERVER:          true,
+	SET_COPYSETS_AVAIL_FLAG:     true,
+	DELETE_FILE:                 true,
+	CREATE_FILE:                 true,
+	EXTEND_FILE:                 true,
+	RECOVER_FILE:                true,
+	UPDATE_FILE_THROTTLE_PARAMS: true,
+	RENAME_FILE:                 true,
+	CHANGE_OWNER:                true,
+	CREATE_SNAPSHOT:             true,
+	DELETE_SNAPSHOT:             true,
+	CREATE_CLONE_FILE:           true,
+	SET_CLONE_FILE_STATUS:       true,
+	OPEN_FILE:                   true,
+	CLOSE_FILE:                  true,
+	DEALLOCATE_SEGMENT:          true,
+}
+
+// clientConnPool returns the pool of a new client and whether the client owns it,
+// clients share baserpc.DefaultConnPool() unless they ask for their own idle timeout.
+func clientConnPool(pool *baserpc.ConnPool, idleTimeoutMs int) (*baserpc.ConnPool, bool) {
+	if pool != nil {
+		return pool, false
+	}
+	if idleTimeoutMs <= 0 {
+		return baserpc.DefaultConnPool(), false
+	}
+	return baserpc.NewConnPool(baserpc.ConnPoolOption{
+		IdleTimeout: time.Duration(idleTimeoutMs * int(time.Millisecond)),
+	}), true
 }
 
 func NewMdsClient(option MdsClientOption) *MdsClient {
+	pool, ownPool := clientConnPool(option.ConnPool, option.ConnIdleTimeoutMs)
 	return &MdsClient{
 		addrs: option.Addrs,
 		baseClient: &baserpc.BaseRpc{
 			Timeout:    time.Duration(option.TimeoutMs * int(time.Millisecond)),
 			RetryTimes: option.RetryTimes,
+			RetryPolicy: baserpc.RetryPolicy{
+				Backoff:            time.Duration(option.RetryBackoffMs * int(time.Millisecond)),
+				MaxBackoff:         time.Duration(option.RetryMaxBackoffMs * int(time.Millisecond)),
+				Deadline:           time.Duration(option.RetryDeadlineMs * int(time.Millisecond)),
+				RetryableStatus:    isRetryableStatus,
+				RetryNonIdempotent: option.RetryNonIdempotent,
+			},
+			Pool: pool,
 		},
+		ownPool:           ownPool,
+		expectedClusterId: option.ExpectedClusterId,
+		credential: Credential{
+			Owner:    option.Owner,
+			Password: option.Password,
+		},
+	}
+}
+
+// Close releases the connections of client if it owns its pool, shared pools
+// are left open.
+func (cli *MdsClient) Close() {
+	if cli.ownPool {
+		cli.baseClient.Pool.Close()
+	}
+}
+
+// Leader returns the address of the mds which is currently used, empty if
+// no mds has been reached yet.
+func (cli *MdsClient) Leader() string {
+	return cli.baseClient.Leader()
+}
+
+// checkCluster makes sure the mds serves the expected cluster before a
+// mutating rpc, the cluster id is fetched only until it matches once.
+func (cli *MdsClient) checkCluster(ctx context.Context, rpcName string) error {
+	if cli.expectedClusterId == "" || !mutatingRpcs[rpcName] {
+		return nil
+	}
+	cli.clusterMu.Lock()
+	verified := cli.clusterVerified
+	cli.clusterMu.Unlock()
+	if verified {
+		return nil
+	}
+
+	info, err := cli.GetClusterInfoWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: check cluster id: %w", rpcName, err)
+	}
+	if info.ClusterId != cli.expectedClusterId {
+		return fmt.Errorf("%s: %w: expected %s, actual %s", rpcName, rpcerr.ErrClusterMismatch,
+			cli.expectedClusterId, info.ClusterId)
+	}
+	cli.clusterMu.Lock()
+	cli.clusterVerified = true
+	cli.clusterMu.Unlock()
+	return nil
+}
+
+type topologyResponse interface {
+	GetStatusCode() int32
+}
+
+type nameserverResponse interface {
+	GetStatusCode() nameserver2.StatusCode
+}
+
+// the mds failed to persist the request, it is worth retrying
+func isRetryableStatus(response interface{}) bool {
+	switch res := response.(type) {
+	case topologyResponse:
+		return res.GetStatusCode() == int32(statuscode.TopoStatusCode_StorgeFail)
+	case nameserverResponse:
+		return res.GetStatusCode() == nameserver2.StatusCode_kStorageError
+	default:
+		return false
 	}
 }
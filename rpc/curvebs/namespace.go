@@ -23,6 +23,7 @@
 package curvebs
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -66,6 +67,8 @@ const (
 	RECOVER_FILE                = "RecoverFile"
 	UPDATE_FILE_THROTTLE_PARAMS = "UpdateFileThrottleParams"
 	FIND_FILE_MOUNTPOINT        = "FindFileMountPoint"
+	RENAME_FILE                 = "RenameFile"
+	CHANGE_OWNER                = "ChangeOwner"
 )
 
 type ThrottleParams struct {
@@ -83,7 +86,8 @@ type FileInfo struct {
 	Owner                string           `json:"owner"`
 	ChunkSize            uint32           `json:"chunkSize"`
 	SegmentSize          uint32           `json:"segmentSize"`
-	Length               uint64           `json:"length"`
+	Length               uint64           `json:"length"`      // in whole GiB
+	LengthBytes          uint64           `json:"lengthBytes"` // exact length in bytes
 	AllocateSize         uint64           `json:"alloc"`
 	Ctime                string           `json:"ctime"`
 	SeqNum               uint64           `json:"seqNum"`
@@ -98,27 +102,42 @@ type FileInfo struct {
 	MountPoints          []string         `json:"mountPoints"`
 }
 
+// allocated size of file and that in each logical pool, in whole GiB
 func (cli *MdsClient) GetFileAllocatedSize(filename string) (uint64, map[uint32]uint64, error) {
-	Rpc := &GetFileAllocatedSize{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, GET_FILE_ALLOC_SIZE_FUNC)
-	Rpc.Request = &nameserver2.GetAllocatedSizeRequest{
-		FileName: &filename,
+	return cli.GetFileAllocatedSizeWithContext(context.Background(), filename)
+}
+
+func (cli *MdsClient) GetFileAllocatedSizeWithContext(ctx context.Context, filename string) (uint64, map[uint32]uint64, error) {
+	size, infos, err := cli.GetFileAllocatedSizeBytesWithContext(ctx, filename)
+	if err != nil {
+		return 0, nil, err
 	}
+	for k, v := range infos {
+		infos[k] = v / common.GiB
+	}
+	return size / common.GiB, infos, nil
+}
 
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return 0, nil, ret.Err
+// the same as GetFileAllocatedSize but in bytes
+func (cli *MdsClient) GetFileAllocatedSizeBytes(filename string) (uint64, map[uint32]uint64, error) {
+	return cli.GetFileAllocatedSizeBytesWithContext(context.Background(), filename)
+}
+
+func (cli *MdsClient) GetFileAllocatedSizeBytesWithContext(ctx context.Context, filename string) (uint64,
+	map[uint32]uint64, error) {
+	request := &nameserver2.GetAllocatedSizeRequest{
+		FileName: &filename,
 	}
-	response := ret.Result.(*nameserver2.GetAllocatedSizeResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != nameserver2.StatusCode_kOK {
-		return 0, nil, fmt.Errorf(nameserver2.StatusCode_name[int32(statusCode)])
+	response, err := callNameServer(ctx, cli, baserpc.NewRpcContext(cli.addrs, GET_FILE_ALLOC_SIZE_FUNC),
+		nameserver2.CurveFSServiceClient.GetAllocatedSize, request)
+	if err != nil {
+		return 0, nil, err
 	}
 	infos := make(map[uint32]uint64)
 	for k, v := range response.GetAllocSizeMap() {
-		infos[k] = v / common.GiB
+		infos[k] = v
 	}
-	return response.GetAllocatedSize() / common.GiB, infos, nil
+	return response.GetAllocatedSize(), infos, nil
 }
 
 func getFileType(t string) nameserver2.FileType {
@@ -213,83 +232,40 @@ func getThrottleType(t string) nameserver2.ThrottleType {
 }
 
 func (cli *MdsClient) ListDir(filename, owner, sig string, date uint64) ([]FileInfo, error) {
-	Rpc := &ListDir{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, LIST_DIR_FUNC)
-	Rpc.Request = &nameserver2.ListDirRequest{
-		FileName: &filename,
-		Owner:    &owner,
-		Date:     &date,
-	}
-	if sig != "" {
-		Rpc.Request.Signature = &sig
-	}
+	return cli.ListDirWithContext(context.Background(), filename, owner, sig, date)
+}
 
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return nil, ret.Err
-	}
-	response := ret.Result.(*nameserver2.ListDirResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != nameserver2.StatusCode_kOK {
-		return nil, fmt.Errorf(nameserver2.StatusCode_name[int32(statusCode)])
+func (cli *MdsClient) ListDirWithContext(ctx context.Context, filename, owner, sig string, date uint64) ([]FileInfo, error) {
+	files, err := cli.listDir(ctx, filename, owner, sig, date)
+	if err != nil {
+		return nil, err
 	}
 	infos := []FileInfo{}
-	for _, v := range response.GetFileInfo() {
-		var info FileInfo
-		info.Id = v.GetId()
-		info.FileName = v.GetFileName()
-		info.ParentId = v.GetParentId()
-		info.FileType = getFileTypeStr(v.GetFileType())
-		info.Owner = v.GetOwner()
-		info.ChunkSize = v.GetChunkSize()
-		info.SegmentSize = v.GetSegmentSize()
-		info.Length = v.GetLength() / common.GiB
-		info.Ctime = time.Unix(int64(v.GetCtime()/1000000), 0).Format(common.TIME_FORMAT)
-		info.SeqNum = v.GetSeqNum()
-		info.FileStatus = getFileStatus(v.GetFileStatus())
-		info.OriginalFullPathName = v.GetOriginalFullPathName()
-		info.CloneSource = v.GetCloneSource()
-		info.CloneLength = v.GetCloneLength()
-		info.StripeUnit = v.GetStripeUnit()
-		info.StripeCount = v.GetStripeCount()
-		info.ThrottleParams = []ThrottleParams{}
-		for _, p := range v.GetThrottleParams().GetThrottleParams() {
-			var param ThrottleParams
-			param.Type = getThrottleTypeStr(p.GetType())
-			param.Limit = p.GetLimit()
-			param.Burst = p.GetBurst()
-			param.BurstLength = p.GetBurstLength()
-			info.ThrottleParams = append(info.ThrottleParams, param)
-		}
-		info.Epoch = v.GetEpoch()
-		infos = append(infos, info)
+	for _, v := range files {
+		infos = append(infos, getFileInfo(v))
 	}
 	return infos, nil
 }
 
-func (cli *MdsClient) GetFileInfo(filename, owner, sig string, date uint64) (FileInfo, error) {
-	info := FileInfo{}
-	Rpc := &GetFileInfo{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, GET_FILE_INFO)
-	Rpc.Request = &nameserver2.GetFileInfoRequest{
+func (cli *MdsClient) listDir(ctx context.Context, filename, owner, sig string, date uint64) ([]*nameserver2.FileInfo, error) {
+	request := &nameserver2.ListDirRequest{
 		FileName: &filename,
 		Owner:    &owner,
 		Date:     &date,
 	}
 	if sig != "" {
-		Rpc.Request.Signature = &sig
-	}
-
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return info, ret.Err
+		request.Signature = &sig
 	}
-	response := ret.Result.(*nameserver2.GetFileInfoResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != nameserver2.StatusCode_kOK {
-		return info, fmt.Errorf(nameserver2.StatusCode_name[int32(statusCode)])
+	response, err := callNameServer(ctx, cli, baserpc.NewRpcContext(cli.addrs, LIST_DIR_FUNC),
+		nameserver2.CurveFSServiceClient.ListDir, request)
+	if err != nil {
+		return nil, err
 	}
-	v := response.GetFileInfo()
+	return response.GetFileInfo(), nil
+}
+
+func getFileInfo(v *nameserver2.FileInfo) FileInfo {
+	var info FileInfo
 	info.Id = v.GetId()
 	info.FileName = v.GetFileName()
 	info.ParentId = v.GetParentId()
@@ -316,148 +292,202 @@ func (cli *MdsClient) GetFileInfo(filename, owner, sig string, date uint64) (Fil
 		info.ThrottleParams = append(info.ThrottleParams, param)
 	}
 	info.Epoch = v.GetEpoch()
-	return info, nil
+	info.LengthBytes = v.GetLength()
+	return info
+}
+
+func (cli *MdsClient) GetFileInfo(filename, owner, sig string, date uint64) (FileInfo, error) {
+	return cli.GetFileInfoWithContext(context.Background(), filename, owner, sig, date)
+}
+
+func (cli *MdsClient) GetFileInfoWithContext(ctx context.Context, filename, owner, sig string, date uint64) (FileInfo, error) {
+	request := &nameserver2.GetFileInfoRequest{
+		FileName: &filename,
+		Owner:    &owner,
+		Date:     &date,
+	}
+	if sig != "" {
+		request.Signature = &sig
+	}
+	response, err := callNameServer(ctx, cli, baserpc.NewRpcContext(cli.addrs, GET_FILE_INFO),
+		nameserver2.CurveFSServiceClient.GetFileInfo, request)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return getFileInfo(response.GetFileInfo()), nil
 }
 
+// size of file in whole GiB
 func (cli *MdsClient) GetFileSize(fileName string) (uint64, error) {
+	return cli.GetFileSizeWithContext(context.Background(), fileName)
+}
+
+func (cli *MdsClient) GetFileSizeWithContext(ctx context.Context, fileName string) (uint64, error) {
+	size, err := cli.GetFileSizeBytesWithContext(ctx, fileName)
+	return size / common.GiB, err
+}
+
+// the same as GetFileSize but in bytes
+func (cli *MdsClient) GetFileSizeBytes(fileName string) (uint64, error) {
+	return cli.GetFileSizeBytesWithContext(context.Background(), fileName)
+}
+
+func (cli *MdsClient) GetFileSizeBytesWithContext(ctx context.Context, fileName string) (uint64, error) {
 	var size uint64
-	Rpc := &GetFileSize{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, GET_FILE_SIZE)
-	Rpc.Request = &nameserver2.GetFileSizeRequest{
+	request := &nameserver2.GetFileSizeRequest{
 		FileName: &fileName,
 	}
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return size, ret.Err
-	}
-
-	response := ret.Result.(*nameserver2.GetFileSizeResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != nameserver2.StatusCode_kOK {
-		return size, fmt.Errorf(nameserver2.StatusCode_name[int32(statusCode)])
+	response, err := callNameServer(ctx, cli, baserpc.NewRpcContext(cli.addrs, GET_FILE_SIZE),
+		nameserver2.CurveFSServiceClient.GetFileSize, request)
+	if err != nil {
+		return size, err
 	}
-	size = response.GetFileSize() / common.GiB
+	size = response.GetFileSize()
 	return size, nil
 }
 
 func (cli *MdsClient) DeleteFile(filename, owner, sig string, fileId, date uint64, forceDelete bool) error {
-	Rpc := &DeleteFile{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, DELETE_FILE)
-	Rpc.Request = &nameserver2.DeleteFileRequest{
+	return cli.DeleteFileWithContext(context.Background(), filename, owner, sig, fileId, date, forceDelete)
+}
+
+func (cli *MdsClient) DeleteFileWithContext(ctx context.Context, filename, owner, sig string, fileId, date uint64, forceDelete bool) error {
+	request := &nameserver2.DeleteFileRequest{
 		FileName:    &filename,
 		Owner:       &owner,
 		Date:        &date,
 		ForceDelete: &forceDelete,
 	}
 	if sig != "" {
-		Rpc.Request.Signature = &sig
+		request.Signature = &sig
 	}
 	if fileId != 0 {
-		Rpc.Request.FileId = &fileId
+		request.FileId = &fileId
 	}
-
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return ret.Err
-	}
-	response := ret.Result.(*nameserver2.DeleteFileResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != nameserver2.StatusCode_kOK {
-		return fmt.Errorf(nameserver2.StatusCode_name[int32(statusCode)])
-	}
-	return nil
+	_, err := callNameServer(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, DELETE_FILE),
+		nameserver2.CurveFSServiceClient.DeleteFile, request)
+	return err
 }
 
 func (cli *MdsClient) RecoverFile(filename, owner, sig string, fileId, date uint64) error {
-	Rpc := &RecoverFile{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, RECOVER_FILE)
-	Rpc.Request = &nameserver2.RecoverFileRequest{
+	return cli.RecoverFileWithContext(context.Background(), filename, owner, sig, fileId, date)
+}
+
+func (cli *MdsClient) RecoverFileWithContext(ctx context.Context, filename, owner, sig string, fileId, date uint64) error {
+	request := &nameserver2.RecoverFileRequest{
 		FileName: &filename,
 		Owner:    &owner,
 		Date:     &date,
 	}
 	if sig != "" {
-		Rpc.Request.Signature = &sig
+		request.Signature = &sig
 	}
 	if fileId != 0 {
-		Rpc.Request.FileId = &fileId
+		request.FileId = &fileId
 	}
-
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return ret.Err
-	}
-	response := ret.Result.(*nameserver2.RecoverFileResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != nameserver2.StatusCode_kOK {
-		return fmt.Errorf(nameserver2.StatusCode_name[int32(statusCode)])
-	}
-	return nil
+	_, err := callNameServer(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, RECOVER_FILE),
+		nameserver2.CurveFSServiceClient.RecoverFile, request)
+	return err
 }
 
 func (cli *MdsClient) CreateFile(filename, ftype, owner, sig string, length, date, stripeUnit, stripeCount uint64) error {
-	Rpc := &CreateFile{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, CREATE_FILE)
+	return cli.CreateFileWithContext(context.Background(), filename, ftype, owner, sig, length, date, stripeUnit, stripeCount)
+}
+
+func (cli *MdsClient) CreateFileWithContext(ctx context.Context, filename, ftype, owner, sig string, length, date, stripeUnit, stripeCount uint64) error {
 	fileType := getFileType(ftype)
-	Rpc.Request = &nameserver2.CreateFileRequest{
+	request := &nameserver2.CreateFileRequest{
 		FileName: &filename,
 		FileType: &fileType,
 		Owner:    &owner,
 		Date:     &date,
 	}
 	if ftype != INODE_DIRECTORY {
-		Rpc.Request.FileLength = &length
+		request.FileLength = &length
 	}
 	if sig != "" {
-		Rpc.Request.Signature = &sig
+		request.Signature = &sig
 	}
 	if stripeCount != 0 && stripeUnit != 0 {
-		Rpc.Request.StripeCount = &stripeCount
-		Rpc.Request.StripeUnit = &stripeUnit
-	}
-
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return ret.Err
-	}
-	response := ret.Result.(*nameserver2.CreateFileResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != nameserver2.StatusCode_kOK {
-		return fmt.Errorf(nameserver2.StatusCode_name[int32(statusCode)])
+		request.StripeCount = &stripeCount
+		request.StripeUnit = &stripeUnit
 	}
-	return nil
+	_, err := callNameServer(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, CREATE_FILE),
+		nameserver2.CurveFSServiceClient.CreateFile, request)
+	return err
 }
 
 func (cli *MdsClient) ExtendFile(filename, owner, sig string, newSize, date uint64) error {
-	Rpc := &ExtendFile{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, EXTEND_FILE)
-	Rpc.Request = &nameserver2.ExtendFileRequest{
+	return cli.ExtendFileWithContext(context.Background(), filename, owner, sig, newSize, date)
+}
+
+func (cli *MdsClient) ExtendFileWithContext(ctx context.Context, filename, owner, sig string, newSize, date uint64) error {
+	request := &nameserver2.ExtendFileRequest{
 		FileName: &filename,
 		NewSize:  &newSize,
 		Owner:    &owner,
 		Date:     &date,
 	}
 	if sig != "" {
-		Rpc.Request.Signature = &sig
+		request.Signature = &sig
 	}
+	_, err := callNameServer(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, EXTEND_FILE),
+		nameserver2.CurveFSServiceClient.ExtendFile, request)
+	return err
+}
 
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return ret.Err
+// rename file, oldFileId and newFileId are checked by mds if they are not 0,
+// newFileId is the id of the file to be overwritten by the rename.
+func (cli *MdsClient) RenameFile(oldFilename, newFilename, owner, sig string, oldFileId, newFileId, date uint64) error {
+	return cli.RenameFileWithContext(context.Background(), oldFilename, newFilename, owner, sig, oldFileId, newFileId, date)
+}
+
+func (cli *MdsClient) RenameFileWithContext(ctx context.Context, oldFilename, newFilename, owner, sig string,
+	oldFileId, newFileId, date uint64) error {
+	request := &nameserver2.RenameFileRequest{
+		OldFileName: &oldFilename,
+		NewFileName: &newFilename,
+		Owner:       &owner,
+		Date:        &date,
+	}
+	if sig != "" {
+		request.Signature = &sig
+	}
+	if oldFileId != 0 {
+		request.OldFileId = &oldFileId
 	}
-	response := ret.Result.(*nameserver2.ExtendFileResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != nameserver2.StatusCode_kOK {
-		return fmt.Errorf(nameserver2.StatusCode_name[int32(statusCode)])
+	if newFileId != 0 {
+		request.NewFileId = &newFileId
 	}
-	return nil
+	_, err := callNameServer(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, RENAME_FILE),
+		nameserver2.CurveFSServiceClient.RenameFile, request)
+	return err
+}
+
+// change owner of file, only root user is allowed, sig is signed by rootOwner
+func (cli *MdsClient) ChangeOwner(filename, newOwner, rootOwner, sig string, date uint64) error {
+	return cli.ChangeOwnerWithContext(context.Background(), filename, newOwner, rootOwner, sig, date)
+}
+
+func (cli *MdsClient) ChangeOwnerWithContext(ctx context.Context, filename, newOwner, rootOwner, sig string, date uint64) error {
+	request := &nameserver2.ChangeOwnerRequest{
+		FileName:  &filename,
+		NewOwner:  &newOwner,
+		RootOwner: &rootOwner,
+		Signature: &sig,
+		Date:      &date,
+	}
+	_, err := callNameServer(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, CHANGE_OWNER),
+		nameserver2.CurveFSServiceClient.ChangeOwner, request)
+	return err
 }
 
 func (cli *MdsClient) UpdateFileThrottleParams(filename, owner, sig string, date uint64, params ThrottleParams) error {
-	Rpc := &UpdateFileThrottleParams{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, UPDATE_FILE_THROTTLE_PARAMS)
+	return cli.UpdateFileThrottleParamsWithContext(context.Background(), filename, owner, sig, date, params)
+}
+
+func (cli *MdsClient) UpdateFileThrottleParamsWithContext(ctx context.Context, filename, owner, sig string, date uint64, params ThrottleParams) error {
 	burstType := getThrottleType(params.Type)
-	Rpc.Request = &nameserver2.UpdateFileThrottleParamsRequest{
+	request := &nameserver2.UpdateFileThrottleParamsRequest{
 		FileName: &filename,
 		Owner:    &owner,
 		Date:     &date,
@@ -469,37 +499,26 @@ func (cli *MdsClient) UpdateFileThrottleParams(filename, owner, sig string, date
 		},
 	}
 	if sig != "" {
-		Rpc.Request.Signature = &sig
-	}
-
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return ret.Err
+		request.Signature = &sig
 	}
-	response := ret.Result.(*nameserver2.UpdateFileThrottleParamsResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != nameserver2.StatusCode_kOK {
-		return fmt.Errorf(nameserver2.StatusCode_name[int32(statusCode)])
-	}
-	return nil
+	_, err := callNameServer(ctx, cli, baserpc.NewRpcContext(cli.addrs, UPDATE_FILE_THROTTLE_PARAMS),
+		nameserver2.CurveFSServiceClient.UpdateFileThrottleParams, request)
+	return err
 }
 
 func (cli *MdsClient) FindFileMountPoint(filename string) ([]string, error) {
+	return cli.FindFileMountPointWithContext(context.Background(), filename)
+}
+
+func (cli *MdsClient) FindFileMountPointWithContext(ctx context.Context, filename string) ([]string, error) {
 	info := []string{}
-	Rpc := &FindFileMountPoint{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, FIND_FILE_MOUNTPOINT)
-	Rpc.Request = &nameserver2.FindFileMountPointRequest{
+	request := &nameserver2.FindFileMountPointRequest{
 		FileName: &filename,
 	}
-
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return nil, ret.Err
-	}
-	response := ret.Result.(*nameserver2.FindFileMountPointResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != nameserver2.StatusCode_kOK {
-		return info, fmt.Errorf(nameserver2.StatusCode_name[int32(statusCode)])
+	response, err := callNameServer(ctx, cli, baserpc.NewRpcContext(cli.addrs, FIND_FILE_MOUNTPOINT),
+		nameserver2.CurveFSServiceClient.FindFileMountPoint, request)
+	if err != nil {
+		return nil, err
 	}
 	for _, v := range response.GetClientInfo() {
 		info = append(info, fmt.Sprintf("%s:%d", v.GetIp(), v.GetPort()))
@@ -0,0 +1,616 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/nameserver2"
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/topology"
+	"github.com/SeanHai/curve-go-rpc/rpc/common"
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
+	"google.golang.org/grpc"
+)
+
+const (
+	fsPort = ":12901"
+)
+
+var (
+	fsGs *grpc.Server
+	fs   = &fsServer{}
+
+	fs_status_ok                   = nameserver2.StatusCode_kOK
+	fs_status_not_exists           = nameserver2.StatusCode_kFileNotExists
+	fs_status_id_not_match         = nameserver2.StatusCode_kFileIdNotMatch
+	fs_status_auth_fail            = nameserver2.StatusCode_kOwnerAuthFail
+	fs_status_session_gone         = nameserver2.StatusCode_kSessionNotExist
+	fs_status_storage_error        = nameserver2.StatusCode_kStorageError
+	fs_status_snap_missing         = nameserver2.StatusCode_kSnapshotFileNotExists
+	file_name                      = "/volume"
+	root_password                  = "root_password"
+	root_owner                     = "root"
+	file_owner                     = "curve"
+	file_id                 uint64 = 10
+	clone_file_name                = "/clone"
+	session_id                     = "session"
+	session_lease_us        uint32 = 200000
+	snapshot_seq            uint64 = 2
+	snapshot_status                = nameserver2.FileStatus_kFileDeleting
+	snapshot_progress       uint32 = 50
+	segment_size            uint32 = 1 << 30
+	chunk_size              uint32 = 16 << 20
+	file_length             uint64 = 500 * common.MiB
+
+	fsClientOption MdsClientOption = MdsClientOption{
+		TimeoutMs:  500,
+		RetryTimes: 3,
+		Addrs:      []string{"127.0.0.1:12901"},
+	}
+)
+
+type fsServer struct {
+	nameserver2.UnimplementedCurveFSServiceServer
+	mu          sync.Mutex
+	cloneStatus nameserver2.FileStatus
+	refreshes   int
+	sessionGone bool
+	// the next storageErrors refreshes fail with kStorageError
+	storageErrors int
+	closed        bool
+	recovered     uint64
+	deleted       []string
+}
+
+func newFileInfo(name string, seq uint64) *nameserver2.FileInfo {
+	fileType := nameserver2.FileType_INODE_PAGEFILE
+	status := nameserver2.FileStatus_kFileCreated
+	return &nameserver2.FileInfo{
+		Id:          &file_id,
+		FileName:    &name,
+		FileType:    &fileType,
+		Owner:       &file_owner,
+		SeqNum:      &seq,
+		FileStatus:  &status,
+		SegmentSize: &segment_size,
+		ChunkSize:   &chunk_size,
+		Length:      &file_length,
+	}
+}
+
+func (s *fsServer) CreateSnapShot(ctx context.Context, req *nameserver2.CreateSnapShotRequest) (
+	*nameserver2.CreateSnapShotResponse, error) {
+	if req.GetFileName() != file_name {
+		return &nameserver2.CreateSnapShotResponse{StatusCode: &fs_status_not_exists}, nil
+	}
+	return &nameserver2.CreateSnapShotResponse{
+		StatusCode:       &fs_status_ok,
+		SnapShotFileInfo: newFileInfo(file_name, snapshot_seq),
+	}, nil
+}
+
+func (s *fsServer) ListSnapShot(ctx context.Context, req *nameserver2.ListSnapShotFileInfoRequest) (
+	*nameserver2.ListSnapShotFileInfoResponse, error) {
+	response := &nameserver2.ListSnapShotFileInfoResponse{StatusCode: &fs_status_ok}
+	for seq := uint64(1); seq <= snapshot_seq; seq++ {
+		response.FileInfo = append(response.FileInfo, newFileInfo(req.GetFileName(), seq))
+	}
+	return response, nil
+}
+
+func (s *fsServer) CheckSnapShotStatus(ctx context.Context, req *nameserver2.CheckSnapShotStatusRequest) (
+	*nameserver2.CheckSnapShotStatusResponse, error) {
+	if req.GetSeq() != snapshot_seq {
+		return &nameserver2.CheckSnapShotStatusResponse{StatusCode: &fs_status_snap_missing}, nil
+	}
+	return &nameserver2.CheckSnapShotStatusResponse{
+		StatusCode: &fs_status_ok,
+		FileStatus: &snapshot_status,
+		Progress:   &snapshot_progress,
+	}, nil
+}
+
+func (s *fsServer) DeleteSnapShot(ctx context.Context, req *nameserver2.DeleteSnapShotRequest) (
+	*nameserver2.DeleteSnapShotResponse, error) {
+	if req.GetSeq() != snapshot_seq {
+		return &nameserver2.DeleteSnapShotResponse{StatusCode: &fs_status_snap_missing}, nil
+	}
+	return &nameserver2.DeleteSnapShotResponse{StatusCode: &fs_status_ok}, nil
+}
+
+func (s *fsServer) GetFileInfo(ctx context.Context, req *nameserver2.GetFileInfoRequest) (
+	*nameserver2.GetFileInfoResponse, error) {
+	info := newFileInfo(req.GetFileName(), 1)
+	if req.GetFileName() == clone_file_name {
+		s.mu.Lock()
+		status := s.cloneStatus
+		s.mu.Unlock()
+		info.FileStatus = &status
+		info.CloneSource = &file_name
+	}
+	return &nameserver2.GetFileInfoResponse{
+		StatusCode: &fs_status_ok,
+		FileInfo:   info,
+	}, nil
+}
+
+func (s *fsServer) CreateCloneFile(ctx context.Context, req *nameserver2.CreateCloneFileRequest) (
+	*nameserver2.CreateCloneFileResponse, error) {
+	s.mu.Lock()
+	s.cloneStatus = nameserver2.FileStatus_kFileCloning
+	s.mu.Unlock()
+	info := newFileInfo(req.GetFileName(), req.GetSeq())
+	info.FileStatus = new(nameserver2.FileStatus)
+	*info.FileStatus = nameserver2.FileStatus_kFileCloning
+	info.CloneSource = req.CloneSource
+	return &nameserver2.CreateCloneFileResponse{
+		StatusCode: &fs_status_ok,
+		FileInfo:   info,
+	}, nil
+}
+
+func (s *fsServer) SetCloneFileStatus(ctx context.Context, req *nameserver2.SetCloneFileStatusRequest) (
+	*nameserver2.SetCloneFileStatusResponse, error) {
+	if req.GetFileName() != clone_file_name {
+		return &nameserver2.SetCloneFileStatusResponse{StatusCode: &fs_status_not_exists}, nil
+	}
+	s.mu.Lock()
+	s.cloneStatus = req.GetFileStatus()
+	s.mu.Unlock()
+	return &nameserver2.SetCloneFileStatusResponse{StatusCode: &fs_status_ok}, nil
+}
+
+func (s *fsServer) RenameFile(ctx context.Context, req *nameserver2.RenameFileRequest) (
+	*nameserver2.RenameFileResponse, error) {
+	if req.GetOldFileName() != file_name {
+		return &nameserver2.RenameFileResponse{StatusCode: &fs_status_not_exists}, nil
+	}
+	if req.OldFileId != nil && req.GetOldFileId() != file_id {
+		return &nameserver2.RenameFileResponse{StatusCode: &fs_status_id_not_match}, nil
+	}
+	return &nameserver2.RenameFileResponse{StatusCode: &fs_status_ok}, nil
+}
+
+func (s *fsServer) ChangeOwner(ctx context.Context, req *nameserver2.ChangeOwnerRequest) (
+	*nameserver2.ChangeOwnerResponse, error) {
+	if req.GetRootOwner() != root_owner ||
+		req.GetSignature() != CalcSignature(root_owner, root_password, req.GetDate()) {
+		return &nameserver2.ChangeOwnerResponse{StatusCode: &fs_status_auth_fail}, nil
+	}
+	return &nameserver2.ChangeOwnerResponse{StatusCode: &fs_status_ok}, nil
+}
+
+func (s *fsServer) OpenFile(ctx context.Context, req *nameserver2.OpenFileRequest) (
+	*nameserver2.OpenFileResponse, error) {
+	s.mu.Lock()
+	s.refreshes = 0
+	s.sessionGone = false
+	s.storageErrors = 0
+	s.closed = false
+	s.mu.Unlock()
+	status := nameserver2.SessionStatus_kSessionOK
+	return &nameserver2.OpenFileResponse{
+		StatusCode: &fs_status_ok,
+		ProtoSession: &nameserver2.ProtoSession{
+			SessionID:     &session_id,
+			CreateTime:    new(uint64),
+			LeaseTime:     &session_lease_us,
+			SessionStatus: &status,
+		},
+		FileInfo: newFileInfo(req.GetFileName(), 1),
+	}, nil
+}
+
+func (s *fsServer) RefreshSession(ctx context.Context, req *nameserver2.ReFreshSessionRequest) (
+	*nameserver2.ReFreshSessionResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessionGone || req.GetSessionID() != session_id {
+		return &nameserver2.ReFreshSessionResponse{
+			StatusCode: &fs_status_session_gone,
+			SessionID:  req.SessionID,
+			Date:       req.Date,
+		}, nil
+	}
+	if s.storageErrors > 0 {
+		s.storageErrors--
+		return &nameserver2.ReFreshSessionResponse{
+			StatusCode: &fs_status_storage_error,
+			SessionID:  req.SessionID,
+			Date:       req.Date,
+		}, nil
+	}
+	s.refreshes++
+	return &nameserver2.ReFreshSessionResponse{
+		StatusCode: &fs_status_ok,
+		SessionID:  req.SessionID,
+		Date:       req.Date,
+		FileInfo:   newFileInfo(req.GetFileName(), 1),
+	}, nil
+}
+
+func (s *fsServer) CloseFile(ctx context.Context, req *nameserver2.CloseFileRequest) (
+	*nameserver2.CloseFileResponse, error) {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	return &nameserver2.CloseFileResponse{StatusCode: &fs_status_ok}, nil
+}
+
+func (s *fsServer) RegistClient(ctx context.Context, req *nameserver2.RegistClientRequest) (
+	*nameserver2.RegistClientResponse, error) {
+	return &nameserver2.RegistClientResponse{StatusCode: &fs_status_ok}, nil
+}
+
+// a tree of /dir1/{dir2/, vol2, skip/} and /vol1, listing /dir1/dir2 is denied
+func (s *fsServer) ListDir(ctx context.Context, req *nameserver2.ListDirRequest) (
+	*nameserver2.ListDirResponse, error) {
+	dir := nameserver2.FileType_INODE_DIRECTORY
+	var names []string
+	switch req.GetFileName() {
+	case "/":
+		names = []string{"dir1/", "vol1"}
+	case "/dir1":
+		names = []string{"dir2/", "vol2", "skip/"}
+	case "/dir1/dir2":
+		return &nameserver2.ListDirResponse{StatusCode: &fs_status_auth_fail}, nil
+	case "/dir1/skip":
+		return nil, fmt.Errorf("excluded %s is listed", req.GetFileName())
+	case RECYCLEBIN_DIR:
+		return &nameserver2.ListDirResponse{StatusCode: &fs_status_ok, FileInfo: newRecycleBinFiles()}, nil
+	}
+	response := &nameserver2.ListDirResponse{StatusCode: &fs_status_ok}
+	for _, name := range names {
+		info := newFileInfo(strings.TrimSuffix(name, "/"), 1)
+		if strings.HasSuffix(name, "/") {
+			info.FileType = &dir
+		}
+		response.FileInfo = append(response.FileInfo, info)
+	}
+	return response, nil
+}
+
+// /dir1/vol1 is deleted twice, and /vol3 has no original path
+func newRecycleBinFiles() []*nameserver2.FileInfo {
+	now := time.Now()
+	files := []struct {
+		name     string
+		id       uint64
+		original string
+		deleted  time.Time
+	}{
+		{"vol1-11", 11, "/dir1/vol1", now.Add(-10 * 24 * time.Hour)},
+		{"vol1-12", 12, "/dir1/vol1", now.Add(-24 * time.Hour)},
+		{"vol3-13", 13, "", now.Add(-20 * 24 * time.Hour)},
+	}
+	infos := []*nameserver2.FileInfo{}
+	for _, f := range files {
+		info := newFileInfo(f.name, 1)
+		info.Id = new(uint64)
+		*info.Id = f.id
+		ctime := uint64(f.deleted.UnixMicro())
+		info.Ctime = &ctime
+		if f.original != "" {
+			info.OriginalFullPathName = &f.original
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+func (s *fsServer) RecoverFile(ctx context.Context, req *nameserver2.RecoverFileRequest) (
+	*nameserver2.RecoverFileResponse, error) {
+	s.mu.Lock()
+	s.recovered = req.GetFileId()
+	s.mu.Unlock()
+	return &nameserver2.RecoverFileResponse{StatusCode: &fs_status_ok}, nil
+}
+
+// deleting /vol3 fails
+func (s *fsServer) DeleteFile(ctx context.Context, req *nameserver2.DeleteFileRequest) (
+	*nameserver2.DeleteFileResponse, error) {
+	if !req.GetForceDelete() || req.GetFileId() == 13 {
+		return &nameserver2.DeleteFileResponse{StatusCode: &fs_status_auth_fail}, nil
+	}
+	s.mu.Lock()
+	s.deleted = append(s.deleted, req.GetFileName())
+	s.mu.Unlock()
+	return &nameserver2.DeleteFileResponse{StatusCode: &fs_status_ok}, nil
+}
+
+// only the first segment of file is allocated
+func (s *fsServer) GetOrAllocateSegment(ctx context.Context, req *nameserver2.GetOrAllocateSegmentRequest) (
+	*nameserver2.GetOrAllocateSegmentResponse, error) {
+	if req.GetAllocateIfNotExist() {
+		return nil, fmt.Errorf("unexpected allocation of %s at %d", req.GetFileName(), req.GetOffset())
+	}
+	if req.GetOffset() >= uint64(segment_size) {
+		code := nameserver2.StatusCode_kSegmentNotAllocated
+		return &nameserver2.GetOrAllocateSegmentResponse{StatusCode: &code}, nil
+	}
+	var startOffset uint64
+	segment := &nameserver2.PageFileSegment{
+		LogicalPoolID: &logical_pool_id,
+		StartOffset:   &startOffset,
+		SegmentSize:   &segment_size,
+		ChunkSize:     &chunk_size,
+	}
+	for i := uint64(0); i < uint64(segment_size/chunk_size); i++ {
+		chunkId := i + 1
+		segment.Chunks = append(segment.Chunks, &nameserver2.PageFileChunkInfo{
+			ChunkID:   &chunkId,
+			CopysetID: &copyset_id,
+		})
+	}
+	return &nameserver2.GetOrAllocateSegmentResponse{
+		StatusCode:      &fs_status_ok,
+		PageFileSegment: segment,
+	}, nil
+}
+
+func (s *fsServer) DeAllocateSegment(ctx context.Context, req *nameserver2.DeAllocateSegmentRequest) (
+	*nameserver2.DeAllocateSegmentResponse, error) {
+	return &nameserver2.DeAllocateSegmentResponse{StatusCode: &fs_status_ok}, nil
+}
+
+func init() {
+	fsGs = grpc.NewServer()
+	nameserver2.RegisterCurveFSServiceServer(fsGs, fs)
+	// chunk locations are looked up in topology of the same mds
+	topology.RegisterTopologyServiceServer(fsGs, &server{})
+	go func() {
+		lis, err := net.Listen("tcp", fsPort)
+		if err != nil {
+			fmt.Printf("failed to listen: %v", err)
+		}
+		if err := fsGs.Serve(lis); err != nil {
+			fmt.Printf("failed to serve: %v", err)
+		}
+	}()
+}
+
+func TestSnapShot(t *testing.T) {
+	mdsClient := NewMdsClient(fsClientOption)
+	defer mdsClient.Close()
+	snapshot, err := mdsClient.CreateSnapShot(file_name, file_owner, "", 0)
+	if err != nil {
+		t.Fatalf("TestSnapShot create rpc failed, error = %v", err)
+	}
+	if snapshot.SeqNum != snapshot_seq || snapshot.FileStatus != FILE_CREATED {
+		t.Errorf("TestSnapShot create response failed, actual snapshot = %+v", snapshot)
+	}
+	if _, err := mdsClient.CreateSnapShot("/unknown", file_owner, "", 0); !errors.Is(err, rpcerr.ErrFileNotExists) {
+		t.Errorf("TestSnapShot expected ErrFileNotExists, actual error = %v", err)
+	}
+
+	snapshots, err := mdsClient.ListSnapShot(file_name, file_owner, "", 0, nil)
+	if err != nil {
+		t.Fatalf("TestSnapShot list rpc failed, error = %v", err)
+	}
+	if len(snapshots) != int(snapshot_seq) {
+		t.Errorf("TestSnapShot list response failed, expected size = %d, actual size = %d", snapshot_seq, len(snapshots))
+	}
+
+	if err := mdsClient.DeleteSnapShot(file_name, file_owner, "", snapshot_seq, 0); err != nil {
+		t.Errorf("TestSnapShot delete rpc failed, error = %v", err)
+	}
+	status, err := mdsClient.CheckSnapShotStatus(file_name, file_owner, "", snapshot_seq, 0)
+	if err != nil {
+		t.Fatalf("TestSnapShot check rpc failed, error = %v", err)
+	}
+	if status.FileStatus != FILE_DELETING || status.Progress != snapshot_progress {
+		t.Errorf("TestSnapShot check response failed, actual status = %+v", status)
+	}
+	_, err = mdsClient.CheckSnapShotStatus(file_name, file_owner, "", snapshot_seq-1, 0)
+	if !errors.Is(err, rpcerr.ErrSnapshotNotExists) {
+		t.Errorf("TestSnapShot expected ErrSnapshotNotExists, actual error = %v", err)
+	}
+}
+
+func TestCloneFile(t *testing.T) {
+	mdsClient := NewMdsClient(fsClientOption)
+	defer mdsClient.Close()
+	info, err := mdsClient.CreateCloneFile(clone_file_name, file_owner, "", file_name, 10*common.GiB, 1,
+		16*1024*1024, 0)
+	if err != nil {
+		t.Fatalf("TestCloneFile create rpc failed, error = %v", err)
+	}
+	if info.FileStatus != FILE_CLONING || info.CloneSource != file_name {
+		t.Errorf("TestCloneFile create response failed, actual info = %+v", info)
+	}
+
+	go func() {
+		time.Sleep(CLONE_STATUS_POLL_INTERVAL)
+		mdsClient.SetCloneFileStatus(clone_file_name, file_owner, "", 0, FILE_CLONED, 0)
+	}()
+	credential := Credential{Owner: file_owner}
+	info, err = mdsClient.WaitCloneFileStatus(clone_file_name, credential, FILE_CLONEMETA_INSTALLED, 5*time.Second)
+	if err != nil {
+		t.Fatalf("TestCloneFile wait failed, error = %v", err)
+	}
+	if info.FileStatus != FILE_CLONED {
+		t.Errorf("TestCloneFile expected status = %s, actual status = %s", FILE_CLONED, info.FileStatus)
+	}
+
+	if err := mdsClient.SetCloneFileStatus(clone_file_name, file_owner, "", 0, FILE_CLONING, 0); err != nil {
+		t.Errorf("TestCloneFile set rpc failed, error = %v", err)
+	}
+	_, err = mdsClient.WaitCloneFileStatus(clone_file_name, credential, FILE_CLONED, 2*CLONE_STATUS_POLL_INTERVAL)
+	if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, rpcerr.ErrTimeout) {
+		t.Errorf("TestCloneFile expected timeout, actual error = %v", err)
+	}
+	if err := mdsClient.SetCloneFileStatus(clone_file_name, file_owner, "", 0, INVALID, 0); !errors.Is(err, rpcerr.ErrInvalidParam) {
+		t.Errorf("TestCloneFile expected ErrInvalidParam, actual error = %v", err)
+	}
+}
+
+func TestRenameAndChangeOwner(t *testing.T) {
+	mdsClient := NewMdsClient(fsClientOption)
+	defer mdsClient.Close()
+	if err := mdsClient.RenameFile(file_name, "/renamed", file_owner, "", file_id, 0, 0); err != nil {
+		t.Errorf("TestRenameAndChangeOwner rename rpc failed, error = %v", err)
+	}
+	err := mdsClient.RenameFile(file_name, "/renamed", file_owner, "", file_id+1, 0, 0)
+	if !errors.Is(err, rpcerr.ErrFileIdNotMatch) {
+		t.Errorf("TestRenameAndChangeOwner expected ErrFileIdNotMatch, actual error = %v", err)
+	}
+
+	sig := CalcSignature(root_owner, root_password, 1)
+	if err := mdsClient.ChangeOwner(file_name, "tenant", root_owner, sig, 1); err != nil {
+		t.Errorf("TestRenameAndChangeOwner change owner rpc failed, error = %v", err)
+	}
+	err = mdsClient.ChangeOwner(file_name, "tenant", file_owner, sig, 1)
+	if !errors.Is(err, rpcerr.ErrOwnerAuthFail) {
+		t.Errorf("TestRenameAndChangeOwner expected ErrOwnerAuthFail, actual error = %v", err)
+	}
+}
+
+func TestSession(t *testing.T) {
+	mdsClient := NewMdsClient(fsClientOption)
+	defer mdsClient.Close()
+	option := SessionOption{
+		Credential: Credential{Owner: file_owner},
+		ClientIp:   "127.0.0.1",
+		ClientPort: 9000,
+	}
+	session, err := mdsClient.OpenSession(context.Background(), file_name, option)
+	if err != nil {
+		t.Fatalf("TestSession open failed, error = %v", err)
+	}
+	if session.Id() != session_id || session.FileInfo().FileName != file_name {
+		t.Errorf("TestSession open response failed, actual session id = %s, file info = %+v",
+			session.Id(), session.FileInfo())
+	}
+	time.Sleep(3 * time.Duration(session_lease_us) * time.Microsecond / SESSION_REFRESH_PER_LEASE)
+	if err := session.Close(context.Background()); err != nil {
+		t.Errorf("TestSession close failed, error = %v", err)
+	}
+	fs.mu.Lock()
+	refreshes, closed := fs.refreshes, fs.closed
+	fs.mu.Unlock()
+	if refreshes == 0 || !closed {
+		t.Errorf("TestSession expected refreshed and closed, actual refreshes = %d, closed = %v", refreshes, closed)
+	}
+
+	session, err = mdsClient.OpenSession(context.Background(), file_name, option)
+	if err != nil {
+		t.Fatalf("TestSession open failed, error = %v", err)
+	}
+	defer session.Close(context.Background())
+	fs.mu.Lock()
+	fs.sessionGone = true
+	fs.mu.Unlock()
+	select {
+	case err := <-session.Lost():
+		if !errors.Is(err, rpcerr.ErrSessionNotExist) {
+			t.Errorf("TestSession expected ErrSessionNotExist, actual error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("TestSession expected session lost")
+	}
+}
+
+func TestSessionStorageError(t *testing.T) {
+	// no retry in rpc, so the storage error reaches the refresh loop
+	option := fsClientOption
+	option.RetryTimes = 1
+	mdsClient := NewMdsClient(option)
+	defer mdsClient.Close()
+	session, err := mdsClient.OpenSession(context.Background(), file_name,
+		SessionOption{Credential: Credential{Owner: file_owner}})
+	if err != nil {
+		t.Fatalf("TestSessionStorageError open failed, error = %v", err)
+	}
+	defer session.Close(context.Background())
+	fs.mu.Lock()
+	fs.storageErrors = 1
+	fs.mu.Unlock()
+	select {
+	case err := <-session.Lost():
+		t.Errorf("TestSessionStorageError expected session kept, actual error = %v", err)
+	case <-time.After(2 * time.Duration(session_lease_us) * time.Microsecond):
+	}
+	fs.mu.Lock()
+	storageErrors, refreshes := fs.storageErrors, fs.refreshes
+	fs.mu.Unlock()
+	if storageErrors != 0 || refreshes == 0 {
+		t.Errorf("TestSessionStorageError expected refreshed after storage error, actual storage errors = %d, refreshes = %d",
+			storageErrors, refreshes)
+	}
+}
+
+func TestSegment(t *testing.T) {
+	mdsClient := NewMdsClient(fsClientOption)
+	defer mdsClient.Close()
+	segment, err := mdsClient.GetSegment(file_name, file_owner, "", uint64(chunk_size), 0)
+	if err != nil {
+		t.Fatalf("TestSegment get rpc failed, error = %v", err)
+	}
+	if segment.LogicalPoolId != logical_pool_id || len(segment.Chunks) != int(segment_size/chunk_size) {
+		t.Errorf("TestSegment get response failed, actual pool = %d, chunks = %d",
+			segment.LogicalPoolId, len(segment.Chunks))
+	}
+	_, err = mdsClient.GetSegment(file_name, file_owner, "", uint64(segment_size), 0)
+	if !errors.Is(err, rpcerr.ErrSegmentNotAllocated) {
+		t.Errorf("TestSegment expected ErrSegmentNotAllocated, actual error = %v", err)
+	}
+	if err := mdsClient.DeAllocateSegment(file_name, file_owner, "", 0, 0); err != nil {
+		t.Errorf("TestSegment deallocate rpc failed, error = %v", err)
+	}
+
+	// the range covers all but the first chunk of allocated segment and an unallocated segment
+	locations, err := mdsClient.GetChunkLocations(file_name, file_owner, "", uint64(chunk_size)+1,
+		2*uint64(segment_size), 0)
+	if err != nil {
+		t.Fatalf("TestSegment locations rpc failed, error = %v", err)
+	}
+	if len(locations) != int(segment_size/chunk_size)-1 {
+		t.Fatalf("TestSegment locations response failed, actual size = %d", len(locations))
+	}
+	first := locations[0]
+	if first.Offset != uint64(chunk_size) || first.ChunkId != 2 || first.CopysetId != copyset_id ||
+		len(first.Peers) != 1 || first.Peers[0].Port != chunkserver_port {
+		t.Errorf("TestSegment locations response failed, actual first location = %+v", first)
+	}
+}
+
+func TestSizeUnit(t *testing.T) {
+	mdsClient := NewMdsClient(fsClientOption)
+	defer mdsClient.Close()
+	info, err := mdsClient.GetFileInfo(file_name, file_owner, "", 0)
+	if err != nil {
+		t.Fatalf("TestSizeUnit get rpc failed, error = %v", err)
+	}
+	// less than 1GiB is truncated to 0 as before
+	if info.Length != 0 || info.LengthBytes != file_length {
+		t.Errorf("TestSizeUnit expected length = 0, length bytes = %d, actual length = %d, length bytes = %d",
+			file_length, info.Length, info.LengthBytes)
+	}
+}
@@ -0,0 +1,155 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
+)
+
+const (
+	// deleted files are moved to RECYCLEBIN_DIR as <name>-<fileId>
+	RECYCLEBIN_DIR = "/RecycleBin"
+)
+
+type RecycleBinEntry struct {
+	FileInfo
+	// full path in recycle bin
+	Path string `json:"path"`
+	// full path before deleted
+	OriginalPath string    `json:"originalPath"`
+	DeleteTime   time.Time `json:"deleteTime"`
+}
+
+// the original path is kept in OriginalFullPathName by mds,
+// otherwise only the file name can be restored from <name>-<fileId>.
+func getOriginalPath(info FileInfo) string {
+	if info.OriginalFullPathName != "" {
+		return info.OriginalFullPathName
+	}
+	name := path.Base(info.FileName)
+	suffix := "-" + strconv.FormatUint(info.Id, 10)
+	return path.Join("/", strings.TrimSuffix(name, suffix))
+}
+
+// list files in recycle bin, the ctime of them is set to the time they are deleted
+func (cli *MdsClient) ListRecycleBin(owner, sig string, date uint64) ([]RecycleBinEntry, error) {
+	return cli.ListRecycleBinWithContext(context.Background(), owner, sig, date)
+}
+
+func (cli *MdsClient) ListRecycleBinWithContext(ctx context.Context, owner, sig string, date uint64) ([]RecycleBinEntry, error) {
+	files, err := cli.listDir(ctx, RECYCLEBIN_DIR, owner, sig, date)
+	if err != nil {
+		return nil, err
+	}
+	entries := []RecycleBinEntry{}
+	for _, v := range files {
+		info := getFileInfo(v)
+		entries = append(entries, RecycleBinEntry{
+			FileInfo:     info,
+			Path:         path.Join(RECYCLEBIN_DIR, path.Base(info.FileName)),
+			OriginalPath: getOriginalPath(info),
+			DeleteTime:   time.UnixMicro(int64(v.GetCtime())),
+		})
+	}
+	return entries, nil
+}
+
+// recover the latest deleted file whose original path is filename,
+// the list and recover rpcs are signed by credential separately.
+func (cli *MdsClient) RecoverRecycleBinFile(filename string, credential Credential) (RecycleBinEntry, error) {
+	return cli.RecoverRecycleBinFileWithContext(context.Background(), filename, credential)
+}
+
+func (cli *MdsClient) RecoverRecycleBinFileWithContext(ctx context.Context, filename string,
+	credential Credential) (RecycleBinEntry, error) {
+	sig, date := credential.Sign()
+	entries, err := cli.ListRecycleBinWithContext(ctx, credential.Owner, sig, date)
+	if err != nil {
+		return RecycleBinEntry{}, err
+	}
+	var latest *RecycleBinEntry
+	for i := range entries {
+		if entries[i].OriginalPath != filename {
+			continue
+		}
+		if latest == nil || entries[i].DeleteTime.After(latest.DeleteTime) {
+			latest = &entries[i]
+		}
+	}
+	if latest == nil {
+		return RecycleBinEntry{}, fmt.Errorf("%s: %w: %s not in %s", RECOVER_FILE, rpcerr.ErrFileNotExists,
+			filename, RECYCLEBIN_DIR)
+	}
+	sig, date = credential.Sign()
+	if err := cli.RecoverFileWithContext(ctx, filename, credential.Owner, sig, latest.Id, date); err != nil {
+		return RecycleBinEntry{}, err
+	}
+	return *latest, nil
+}
+
+// delete files deleted before expiration ago from recycle bin permanently,
+// failure of an entry doesn't stop the others, the expired entries and
+// the first error are returned. Every rpc is signed by credential separately
+// since signatures expire during a long expiration.
+func (cli *MdsClient) ExpireRecycleBin(credential Credential, expiration time.Duration) ([]RecycleBinEntry, error) {
+	return cli.ExpireRecycleBinWithContext(context.Background(), credential, expiration)
+}
+
+func (cli *MdsClient) ExpireRecycleBinWithContext(ctx context.Context, credential Credential,
+	expiration time.Duration) ([]RecycleBinEntry, error) {
+	sig, date := credential.Sign()
+	entries, err := cli.ListRecycleBinWithContext(ctx, credential.Owner, sig, date)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(-expiration)
+	expired := []RecycleBinEntry{}
+	var firstErr error
+	failed := 0
+	for _, entry := range entries {
+		if !entry.DeleteTime.Before(deadline) {
+			continue
+		}
+		sig, date := credential.Sign()
+		err := cli.DeleteFileWithContext(ctx, entry.Path, credential.Owner, sig, entry.Id, date, true)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+			continue
+		}
+		expired = append(expired, entry)
+	}
+	if firstErr != nil {
+		return expired, fmt.Errorf("%d of %d expired files are not deleted: %w", failed, failed+len(expired), firstErr)
+	}
+	return expired, nil
+}
@@ -0,0 +1,79 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
+)
+
+func TestRecycleBin(t *testing.T) {
+	mdsClient := NewMdsClient(fsClientOption)
+	defer mdsClient.Close()
+
+	entries, err := mdsClient.ListRecycleBin(root_owner, "", 0)
+	if err != nil {
+		t.Fatalf("TestRecycleBin list rpc failed, error = %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("TestRecycleBin list response failed, actual size = %d", len(entries))
+	}
+	if entries[0].Path != "/RecycleBin/vol1-11" || entries[0].OriginalPath != "/dir1/vol1" ||
+		entries[2].OriginalPath != "/vol3" {
+		t.Errorf("TestRecycleBin list response failed, actual entries = %+v", entries)
+	}
+	if age := time.Since(entries[1].DeleteTime); age < 23*time.Hour || age > 25*time.Hour {
+		t.Errorf("TestRecycleBin expected deleted a day ago, actual delete time = %v", entries[1].DeleteTime)
+	}
+
+	entry, err := mdsClient.RecoverRecycleBinFile("/dir1/vol1", Credential{Owner: root_owner})
+	if err != nil {
+		t.Fatalf("TestRecycleBin recover rpc failed, error = %v", err)
+	}
+	fs.mu.Lock()
+	recovered := fs.recovered
+	fs.mu.Unlock()
+	if entry.Id != 12 || recovered != 12 {
+		t.Errorf("TestRecycleBin expected the latest one recovered, actual entry = %d, recovered = %d", entry.Id, recovered)
+	}
+	_, err = mdsClient.RecoverRecycleBinFile("/unknown", Credential{Owner: root_owner})
+	if !errors.Is(err, rpcerr.ErrFileNotExists) {
+		t.Errorf("TestRecycleBin expected ErrFileNotExists, actual error = %v", err)
+	}
+
+	// /vol3 is expired too but fails to be deleted
+	expired, err := mdsClient.ExpireRecycleBin(Credential{Owner: root_owner}, 7*24*time.Hour)
+	if !errors.Is(err, rpcerr.ErrOwnerAuthFail) {
+		t.Errorf("TestRecycleBin expected ErrOwnerAuthFail, actual error = %v", err)
+	}
+	fs.mu.Lock()
+	deleted := fs.deleted
+	fs.mu.Unlock()
+	if len(expired) != 1 || expired[0].Id != 11 || !reflect.DeepEqual(deleted, []string{"/RecycleBin/vol1-11"}) {
+		t.Errorf("TestRecycleBin expire response failed, actual expired = %+v, deleted = %v", expired, deleted)
+	}
+}
@@ -24,311 +24,171 @@ package curvebs
 
 import (
 	"context"
+	"errors"
 
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/chunk"
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/cli2"
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/copyset"
 	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/nameserver2"
 	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/topology"
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/topology/statuscode"
 	"github.com/SeanHai/curve-go-rpc/rpc/baserpc"
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
-// topology
-// list physical pool
-type ListPhysicalPoolRpc struct {
-	ctx     *baserpc.RpcContext
-	client  topology.TopologyServiceClient
-	Request *topology.ListPhysicalPoolRequest
-}
-
-func (rpc *ListPhysicalPoolRpc) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = topology.NewTopologyServiceClient(cc)
-}
-
-func (rpc *ListPhysicalPoolRpc) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.ListPhysicalPool(ctx, rpc.Request, opt...)
-}
-
-// list logical pool
-type ListLogicalPoolRpc struct {
-	ctx     *baserpc.RpcContext
-	client  topology.TopologyServiceClient
-	Request *topology.ListLogicalPoolRequest
-}
-
-func (rpc *ListLogicalPoolRpc) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = topology.NewTopologyServiceClient(cc)
-}
-
-func (rpc *ListLogicalPoolRpc) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.ListLogicalPool(ctx, rpc.Request, opt...)
-}
-
-// list zones of logical pool
-type ListPoolZonesRpc struct {
-	ctx     *baserpc.RpcContext
-	client  topology.TopologyServiceClient
-	Request *topology.ListPoolZoneRequest
-}
-
-func (rpc *ListPoolZonesRpc) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = topology.NewTopologyServiceClient(cc)
-}
-
-func (rpc *ListPoolZonesRpc) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.ListPoolZone(ctx, rpc.Request, opt...)
-}
-
-// list servers of zone
-type ListZoneServer struct {
-	ctx     *baserpc.RpcContext
-	client  topology.TopologyServiceClient
-	Request *topology.ListZoneServerRequest
-}
-
-func (rpc *ListZoneServer) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = topology.NewTopologyServiceClient(cc)
-}
-
-func (rpc *ListZoneServer) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.ListZoneServer(ctx, rpc.Request, opt...)
-}
-
-// list chunkservers of server
-type ListChunkServer struct {
-	ctx     *baserpc.RpcContext
-	client  topology.TopologyServiceClient
-	Request *topology.ListChunkServerRequest
-}
-
-func (rpc *ListChunkServer) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = topology.NewTopologyServiceClient(cc)
-}
-
-func (rpc *ListChunkServer) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.ListChunkServer(ctx, rpc.Request, opt...)
-}
-
-// get chunkserver in cluster
-type GetChunkServerInCluster struct {
-	ctx     *baserpc.RpcContext
-	client  topology.TopologyServiceClient
-	Request *topology.GetChunkServerInClusterRequest
-}
-
-func (rpc *GetChunkServerInCluster) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = topology.NewTopologyServiceClient(cc)
-}
-
-func (rpc *GetChunkServerInCluster) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.GetChunkServerInCluster(ctx, rpc.Request, opt...)
-}
-
-// get copysets on chunkserver
-type GetCopySetsInChunkServer struct {
-	ctx     *baserpc.RpcContext
-	client  topology.TopologyServiceClient
-	Request *topology.GetCopySetsInChunkServerRequest
-}
-
-func (rpc *GetCopySetsInChunkServer) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = topology.NewTopologyServiceClient(cc)
-}
-
-func (rpc *GetCopySetsInChunkServer) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.GetCopySetsInChunkServer(ctx, rpc.Request, opt...)
-}
-
-// get chunkserver list in copysets
-type GetChunkServerListInCopySets struct {
-	ctx     *baserpc.RpcContext
-	client  topology.TopologyServiceClient
-	Request *topology.GetChunkServerListInCopySetsRequest
-}
-
-func (rpc *GetChunkServerListInCopySets) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = topology.NewTopologyServiceClient(cc)
-}
-
-func (rpc *GetChunkServerListInCopySets) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.GetChunkServerListInCopySets(ctx, rpc.Request, opt...)
-}
-
-// get copysets in cluster
-type GetCopySetsInCluster struct {
-	ctx     *baserpc.RpcContext
-	client  topology.TopologyServiceClient
-	Request *topology.GetCopySetsInClusterRequest
-}
-
-func (rpc *GetCopySetsInCluster) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = topology.NewTopologyServiceClient(cc)
-}
-
-func (rpc *GetCopySetsInCluster) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.GetCopySetsInCluster(ctx, rpc.Request, opt...)
-}
-
-// get logical pool
-type GetLogicalPool struct {
-	ctx     *baserpc.RpcContext
-	client  topology.TopologyServiceClient
-	Request *topology.GetLogicalPoolRequest
-}
-
-func (rpc *GetLogicalPool) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = topology.NewTopologyServiceClient(cc)
-}
+// a new rpc only needs its method expression, e.g.
+//
+//	response, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, LIST_PHYSICAL_POOL_FUNC),
+//		topology.TopologyServiceClient.ListPhysicalPool, &topology.ListPhysicalPoolRequest{})
 
-func (rpc *GetLogicalPool) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.GetLogicalPool(ctx, rpc.Request, opt...)
+// topology
+type topologyMethod[Req, Resp any] func(topology.TopologyServiceClient, context.Context, Req,
+	...grpc.CallOption) (Resp, error)
+
+func checkTopologyStatus[Resp topologyResponse](name string, response Resp) error {
+	statusCode := response.GetStatusCode()
+	if statusCode != int32(statuscode.TopoStatusCode_Success) {
+		return rpcerr.NewTopologyError(name, statusCode)
+	}
+	return nil
+}
+
+func callTopology[Req any, Resp topologyResponse](ctx context.Context, cli *MdsClient, rpcCtx *baserpc.RpcContext,
+	method topologyMethod[Req, Resp], request Req) (Resp, error) {
+	if err := cli.checkCluster(ctx, rpcCtx.Name()); err != nil {
+		var response Resp
+		return response, err
+	}
+	newStub := func(cc grpc.ClientConnInterface) baserpc.UnaryFunc[Req, Resp] {
+		client := topology.NewTopologyServiceClient(cc)
+		return func(ctx context.Context, in Req, opts ...grpc.CallOption) (Resp, error) {
+			return method(client, ctx, in, opts...)
+		}
+	}
+	return baserpc.Call(ctx, cli.baseClient, rpcCtx, newStub, request, checkTopologyStatus[Resp])
 }
 
 // nameserver2
-// get file/dir size
-type GetFileSize struct {
-	ctx     *baserpc.RpcContext
-	client  nameserver2.CurveFSServiceClient
-	Request *nameserver2.GetFileSizeRequest
-}
-
-func (rpc *GetFileSize) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = nameserver2.NewCurveFSServiceClient(cc)
-}
-
-func (rpc *GetFileSize) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.GetFileSize(ctx, rpc.Request, opt...)
-}
-
-// get file(include dir) allocated space
-type GetFileAllocatedSize struct {
-	ctx     *baserpc.RpcContext
-	client  nameserver2.CurveFSServiceClient
-	Request *nameserver2.GetAllocatedSizeRequest
-}
-
-func (rpc *GetFileAllocatedSize) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = nameserver2.NewCurveFSServiceClient(cc)
-}
-
-func (rpc *GetFileAllocatedSize) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.GetAllocatedSize(ctx, rpc.Request, opt...)
-}
-
-// list volume in dir
-type ListDir struct {
-	ctx     *baserpc.RpcContext
-	client  nameserver2.CurveFSServiceClient
-	Request *nameserver2.ListDirRequest
-}
-
-func (rpc *ListDir) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = nameserver2.NewCurveFSServiceClient(cc)
-}
-
-func (rpc *ListDir) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.ListDir(ctx, rpc.Request, opt...)
-}
-
-// get volume
-type GetFileInfo struct {
-	ctx     *baserpc.RpcContext
-	client  nameserver2.CurveFSServiceClient
-	Request *nameserver2.GetFileInfoRequest
-}
-
-func (rpc *GetFileInfo) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = nameserver2.NewCurveFSServiceClient(cc)
-}
-
-func (rpc *GetFileInfo) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.GetFileInfo(ctx, rpc.Request, opt...)
-}
-
-// delete volume
-type DeleteFile struct {
-	ctx     *baserpc.RpcContext
-	client  nameserver2.CurveFSServiceClient
-	Request *nameserver2.DeleteFileRequest
-}
-
-func (rpc *DeleteFile) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = nameserver2.NewCurveFSServiceClient(cc)
-}
-
-func (rpc *DeleteFile) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.DeleteFile(ctx, rpc.Request, opt...)
+type nameServerMethod[Req, Resp any] func(nameserver2.CurveFSServiceClient, context.Context, Req,
+	...grpc.CallOption) (Resp, error)
+
+func checkNameServerStatus[Resp nameserverResponse](name string, response Resp) error {
+	statusCode := response.GetStatusCode()
+	if statusCode != nameserver2.StatusCode_kOK {
+		return rpcerr.NewNameServerError(name, statusCode)
+	}
+	return nil
+}
+
+func callNameServer[Req any, Resp nameserverResponse](ctx context.Context, cli *MdsClient,
+	rpcCtx *baserpc.RpcContext, method nameServerMethod[Req, Resp], request Req) (Resp, error) {
+	if err := cli.checkCluster(ctx, rpcCtx.Name()); err != nil {
+		var response Resp
+		return response, err
+	}
+	newStub := func(cc grpc.ClientConnInterface) baserpc.UnaryFunc[Req, Resp] {
+		client := nameserver2.NewCurveFSServiceClient(cc)
+		return func(ctx context.Context, in Req, opts ...grpc.CallOption) (Resp, error) {
+			return method(client, ctx, in, opts...)
+		}
+	}
+	return baserpc.Call(ctx, cli.baseClient, rpcCtx, newStub, request, checkNameServerStatus[Resp])
+}
+
+// cli2, the responses carry no status code and raft errors are returned as rpc errors
+type cli2Method[Req, Resp any] func(cli2.CliService2Client, context.Context, Req,
+	...grpc.CallOption) (Resp, error)
+
+func callCli2[Req, Resp any](ctx context.Context, cli *ChunkserverClient, rpcCtx *baserpc.RpcContext,
+	method cli2Method[Req, Resp], request Req) (Resp, error) {
+	newStub := func(cc grpc.ClientConnInterface) baserpc.UnaryFunc[Req, Resp] {
+		client := cli2.NewCliService2Client(cc)
+		return func(ctx context.Context, in Req, opts ...grpc.CallOption) (Resp, error) {
+			return method(client, ctx, in, opts...)
+		}
+	}
+	return baserpc.Call(ctx, cli.baseClient, rpcCtx, newStub, request, nil)
+}
+
+// copyset
+type copysetResponse interface {
+	GetStatus() copyset.COPYSET_OP_STATUS
+}
+
+type copysetMethod[Req, Resp any] func(copyset.CopysetServiceClient, context.Context, Req,
+	...grpc.CallOption) (Resp, error)
+
+func checkCopysetStatus[Resp copysetResponse](name string, response Resp) error {
+	status := response.GetStatus()
+	if status != copyset.COPYSET_OP_STATUS_COPYSET_OP_STATUS_SUCCESS {
+		return rpcerr.NewCopysetError(name, status)
+	}
+	return nil
+}
+
+func callCopyset[Req any, Resp copysetResponse](ctx context.Context, cli *ChunkserverClient,
+	rpcCtx *baserpc.RpcContext, method copysetMethod[Req, Resp], request Req) (Resp, error) {
+	newStub := func(cc grpc.ClientConnInterface) baserpc.UnaryFunc[Req, Resp] {
+		client := copyset.NewCopysetServiceClient(cc)
+		return func(ctx context.Context, in Req, opts ...grpc.CallOption) (Resp, error) {
+			return method(client, ctx, in, opts...)
+		}
+	}
+	return baserpc.Call(ctx, cli.baseClient, rpcCtx, newStub, request, checkCopysetStatus[Resp])
+}
+
+// chunk
+type chunkResponse interface {
+	GetStatus() chunk.CHUNK_OP_STATUS
+	GetRedirect() string
+}
+
+type chunkMethod[Req, Resp any] func(chunk.ChunkServiceClient, context.Context, Req,
+	...grpc.CallOption) (Resp, error)
+
+func checkChunkStatus[Resp chunkResponse](name string, response Resp) error {
+	status := response.GetStatus()
+	if status != chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_SUCCESS {
+		return rpcerr.NewChunkError(name, status)
+	}
+	return nil
+}
+
+// callChunk sends request to peer and follows the redirections to the leader
+// at most MAX_CHUNK_REDIRECT times. The attachment is sent and received in
+// the metadata CHUNK_ATTACHMENT_KEY.
+func callChunk[Req any, Resp chunkResponse](ctx context.Context, cli *ChunkserverClient, peer Peer, name string,
+	idempotent bool, method chunkMethod[Req, Resp], request Req, attachment []byte) (Resp, []byte, error) {
+	if attachment != nil {
+		ctx = metadata.AppendToOutgoingContext(ctx, CHUNK_ATTACHMENT_KEY, string(attachment))
+	}
+	for redirect := 0; ; redirect++ {
+		var response Resp
+		rpcCtx, err := peerRpcContext(peer, name, idempotent)
+		if err != nil {
+			return response, nil, err
+		}
+		var header metadata.MD
+		newStub := func(cc grpc.ClientConnInterface) baserpc.UnaryFunc[Req, Resp] {
+			client := chunk.NewChunkServiceClient(cc)
+			return func(ctx context.Context, in Req, opts ...grpc.CallOption) (Resp, error) {
+				return method(client, ctx, in, append(opts, grpc.Header(&header))...)
+			}
+		}
+		response, err = baserpc.Call(ctx, cli.baseClient, rpcCtx, newStub, request, checkChunkStatus[Resp])
+		if errors.Is(err, rpcerr.ErrRedirected) && response.GetRedirect() != "" && redirect < MAX_CHUNK_REDIRECT {
+			peer = Peer{Address: response.GetRedirect()}
+			continue
+		}
+		if err != nil {
+			return response, nil, err
+		}
+		var data []byte
+		if values := header.Get(CHUNK_ATTACHMENT_KEY); len(values) > 0 {
+			data = []byte(values[0])
+		}
+		return response, data, nil
+	}
 }
-
-// recover volume
-type RecoverFile struct {
-	ctx     *baserpc.RpcContext
-	client  nameserver2.CurveFSServiceClient
-	Request *nameserver2.RecoverFileRequest
-}
-
-func (rpc *RecoverFile) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = nameserver2.NewCurveFSServiceClient(cc)
-}
-
-func (rpc *RecoverFile) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.RecoverFile(ctx, rpc.Request, opt...)
-}
-
-// create volume
-type CreateFile struct {
-	ctx     *baserpc.RpcContext
-	client  nameserver2.CurveFSServiceClient
-	Request *nameserver2.CreateFileRequest
-}
-
-func (rpc *CreateFile) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = nameserver2.NewCurveFSServiceClient(cc)
-}
-
-func (rpc *CreateFile) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.CreateFile(ctx, rpc.Request, opt...)
-}
-
-// extend volume
-type ExtendFile struct {
-	ctx     *baserpc.RpcContext
-	client  nameserver2.CurveFSServiceClient
-	Request *nameserver2.ExtendFileRequest
-}
-
-func (rpc *ExtendFile) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = nameserver2.NewCurveFSServiceClient(cc)
-}
-
-func (rpc *ExtendFile) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.ExtendFile(ctx, rpc.Request, opt...)
-}
-
-// update volume throttle params
-type UpdateFileThrottleParams struct {
-	ctx     *baserpc.RpcContext
-	client  nameserver2.CurveFSServiceClient
-	Request *nameserver2.UpdateFileThrottleParamsRequest
-}
-
-func (rpc *UpdateFileThrottleParams) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = nameserver2.NewCurveFSServiceClient(cc)
-}
-
-func (rpc *UpdateFileThrottleParams) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.UpdateFileThrottleParams(ctx, rpc.Request, opt...)
-}
-
-// find volume mountpoints
-type FindFileMountPoint struct {
-	ctx     *baserpc.RpcContext
-	client  nameserver2.CurveFSServiceClient
-	Request *nameserver2.FindFileMountPointRequest
-}
-
-func (rpc *FindFileMountPoint) NewRpcClient(cc grpc.ClientConnInterface) {
-	rpc.client = nameserver2.NewCurveFSServiceClient(cc)
-}
-
-func (rpc *FindFileMountPoint) Stub_Func(ctx context.Context, opt ...grpc.CallOption) (interface{}, error) {
-	return rpc.client.FindFileMountPoint(ctx, rpc.Request, opt...)
-}
\ No newline at end of file
@@ -0,0 +1,191 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/nameserver2"
+	"github.com/SeanHai/curve-go-rpc/rpc/baserpc"
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
+)
+
+const (
+	// apis
+	GET_OR_ALLOCATE_SEGMENT = "GetOrAllocateSegment"
+	DEALLOCATE_SEGMENT      = "DeAllocateSegment"
+)
+
+type Chunk struct {
+	ChunkId   uint64 `json:"chunkId"`
+	CopysetId uint32 `json:"copysetId"`
+}
+
+// Segment is the allocated space of file from StartOffset, its i-th chunk
+// starts at StartOffset + i*ChunkSize of file.
+type Segment struct {
+	LogicalPoolId uint32  `json:"logicalPoolId"`
+	StartOffset   uint64  `json:"startOffset"`
+	SegmentSize   uint32  `json:"segmentSize"`
+	ChunkSize     uint32  `json:"chunkSize"`
+	Chunks        []Chunk `json:"chunks"`
+}
+
+// ChunkLocation is where the chunk at Offset of file is stored
+type ChunkLocation struct {
+	Offset        uint64                `json:"offset"`
+	SegmentOffset uint64                `json:"segmentOffset"`
+	ChunkId       uint64                `json:"chunkId"`
+	LogicalPoolId uint32                `json:"logicalPoolId"`
+	CopysetId     uint32                `json:"copysetId"`
+	Peers         []ChunkServerLocation `json:"peers"`
+}
+
+// get the segment containing offset without allocating it,
+// rpcerr.ErrSegmentNotAllocated is returned if it is not allocated yet.
+func (cli *MdsClient) GetSegment(filename, owner, sig string, offset, date uint64) (Segment, error) {
+	return cli.GetSegmentWithContext(context.Background(), filename, owner, sig, offset, date)
+}
+
+func (cli *MdsClient) GetSegmentWithContext(ctx context.Context, filename, owner, sig string, offset, date uint64) (Segment, error) {
+	allocate := false
+	request := &nameserver2.GetOrAllocateSegmentRequest{
+		FileName:           &filename,
+		Offset:             &offset,
+		AllocateIfNotExist: &allocate,
+		Owner:              &owner,
+		Date:               &date,
+	}
+	if sig != "" {
+		request.Signature = &sig
+	}
+	response, err := callNameServer(ctx, cli, baserpc.NewRpcContext(cli.addrs, GET_OR_ALLOCATE_SEGMENT),
+		nameserver2.CurveFSServiceClient.GetOrAllocateSegment, request)
+	if err != nil {
+		return Segment{}, err
+	}
+	v := response.GetPageFileSegment()
+	segment := Segment{
+		LogicalPoolId: v.GetLogicalPoolID(),
+		StartOffset:   v.GetStartOffset(),
+		SegmentSize:   v.GetSegmentSize(),
+		ChunkSize:     v.GetChunkSize(),
+		Chunks:        []Chunk{},
+	}
+	for _, chunk := range v.GetChunks() {
+		segment.Chunks = append(segment.Chunks, Chunk{
+			ChunkId:   chunk.GetChunkID(),
+			CopysetId: chunk.GetCopysetID(),
+		})
+	}
+	return segment, nil
+}
+
+// free the segment containing offset, the data in it is lost
+func (cli *MdsClient) DeAllocateSegment(filename, owner, sig string, offset, date uint64) error {
+	return cli.DeAllocateSegmentWithContext(context.Background(), filename, owner, sig, offset, date)
+}
+
+func (cli *MdsClient) DeAllocateSegmentWithContext(ctx context.Context, filename, owner, sig string, offset, date uint64) error {
+	request := &nameserver2.DeAllocateSegmentRequest{
+		FileName: &filename,
+		Offset:   &offset,
+		Owner:    &owner,
+		Date:     &date,
+	}
+	if sig != "" {
+		request.Signature = &sig
+	}
+	_, err := callNameServer(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, DEALLOCATE_SEGMENT),
+		nameserver2.CurveFSServiceClient.DeAllocateSegment, request)
+	return err
+}
+
+// get locations of the allocated chunks in [offset, offset+length) of file,
+// offset and length are in bytes. Chunks of unallocated segments are skipped.
+func (cli *MdsClient) GetChunkLocations(filename, owner, sig string, offset, length, date uint64) ([]ChunkLocation, error) {
+	return cli.GetChunkLocationsWithContext(context.Background(), filename, owner, sig, offset, length, date)
+}
+
+func (cli *MdsClient) GetChunkLocationsWithContext(ctx context.Context, filename, owner, sig string,
+	offset, length, date uint64) ([]ChunkLocation, error) {
+	info, err := cli.GetFileInfoWithContext(ctx, filename, owner, sig, date)
+	if err != nil {
+		return nil, err
+	}
+	segmentSize := uint64(info.SegmentSize)
+	chunkSize := uint64(info.ChunkSize)
+	if segmentSize == 0 || chunkSize == 0 {
+		return nil, fmt.Errorf("%s: %w: segment size %d, chunk size %d of %s", GET_OR_ALLOCATE_SEGMENT,
+			rpcerr.ErrInvalidParam, segmentSize, chunkSize, filename)
+	}
+
+	locations := []ChunkLocation{}
+	end := offset + length
+	for segOffset := offset / segmentSize * segmentSize; segOffset < end; segOffset += segmentSize {
+		segment, err := cli.GetSegmentWithContext(ctx, filename, owner, sig, segOffset, date)
+		if errors.Is(err, rpcerr.ErrSegmentNotAllocated) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i, chunk := range segment.Chunks {
+			chunkOffset := segment.StartOffset + uint64(i)*uint64(segment.ChunkSize)
+			if chunkOffset+uint64(segment.ChunkSize) <= offset || chunkOffset >= end {
+				continue
+			}
+			locations = append(locations, ChunkLocation{
+				Offset:        chunkOffset,
+				SegmentOffset: segment.StartOffset,
+				ChunkId:       chunk.ChunkId,
+				LogicalPoolId: segment.LogicalPoolId,
+				CopysetId:     chunk.CopysetId,
+			})
+		}
+	}
+	if len(locations) == 0 {
+		return locations, nil
+	}
+
+	// look up peers once for each copyset
+	copysets := []CopySet{}
+	index := make(map[CopySetInfo]int)
+	for _, loc := range locations {
+		key := CopySetInfo{LogicalPoolId: loc.LogicalPoolId, CopysetId: loc.CopysetId}
+		if _, ok := index[key]; !ok {
+			index[key] = len(copysets)
+			copysets = append(copysets, CopySet{CopySetInfo: key})
+		}
+	}
+	if err := cli.fillCopySetPeers(ctx, copysets); err != nil {
+		return nil, err
+	}
+	for i := range locations {
+		key := CopySetInfo{LogicalPoolId: locations[i].LogicalPoolId, CopysetId: locations[i].CopysetId}
+		locations[i].Peers = copysets[index[key]].Peers
+	}
+	return locations, nil
+}
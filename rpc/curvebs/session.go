@@ -0,0 +1,257 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/nameserver2"
+	"github.com/SeanHai/curve-go-rpc/rpc/baserpc"
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
+)
+
+const (
+	// the session is refreshed SESSION_REFRESH_PER_LEASE times in a lease by default
+	SESSION_REFRESH_PER_LEASE = 4
+
+	// apis
+	OPEN_FILE       = "OpenFile"
+	CLOSE_FILE      = "CloseFile"
+	REFRESH_SESSION = "RefreshSession"
+	REGIST_CLIENT   = "RegistClient"
+)
+
+type SessionOption struct {
+	// owner of file, each request is signed again
+	Credential Credential
+	// address of the client reported to mds, shown by FindFileMountPoint
+	ClientIp      string
+	ClientPort    uint32
+	ClientVersion string
+	// 0 means lease time / SESSION_REFRESH_PER_LEASE
+	RefreshInterval time.Duration
+}
+
+// Session keeps a file open by refreshing its lease in background, the file
+// can not be deleted until the session is closed.
+type Session struct {
+	cli       *MdsClient
+	filename  string
+	option    SessionOption
+	sessionId string
+	leaseTime time.Duration
+
+	mu       sync.Mutex
+	fileInfo FileInfo
+
+	lost      chan error
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// regist the client address to mds, the address is reported in FindFileMountPoint
+func (cli *MdsClient) RegistClient(ip string, port uint32) error {
+	return cli.RegistClientWithContext(context.Background(), ip, port)
+}
+
+func (cli *MdsClient) RegistClientWithContext(ctx context.Context, ip string, port uint32) error {
+	request := &nameserver2.RegistClientRequest{
+		Ip:   &ip,
+		Port: &port,
+	}
+	_, err := callNameServer(ctx, cli, baserpc.NewRpcContext(cli.addrs, REGIST_CLIENT),
+		nameserver2.CurveFSServiceClient.RegistClient, request)
+	return err
+}
+
+// OpenSession opens filename and refreshes the session until Close, the
+// client is registered first if option.ClientIp is set.
+func (cli *MdsClient) OpenSession(ctx context.Context, filename string, option SessionOption) (*Session, error) {
+	if option.ClientIp != "" {
+		if err := cli.RegistClientWithContext(ctx, option.ClientIp, option.ClientPort); err != nil {
+			return nil, err
+		}
+	}
+
+	sig, date := option.Credential.Sign()
+	request := &nameserver2.OpenFileRequest{
+		FileName: &filename,
+		Owner:    &option.Credential.Owner,
+		Date:     &date,
+	}
+	if sig != "" {
+		request.Signature = &sig
+	}
+	if option.ClientVersion != "" {
+		request.ClientVersion = &option.ClientVersion
+	}
+	response, err := callNameServer(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, OPEN_FILE),
+		nameserver2.CurveFSServiceClient.OpenFile, request)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &Session{
+		cli:       cli,
+		filename:  filename,
+		option:    option,
+		sessionId: response.GetProtoSession().GetSessionID(),
+		leaseTime: time.Duration(response.GetProtoSession().GetLeaseTime()) * time.Microsecond,
+		fileInfo:  getFileInfo(response.GetFileInfo()),
+		lost:      make(chan error, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	interval := option.RefreshInterval
+	if interval <= 0 {
+		interval = session.leaseTime / SESSION_REFRESH_PER_LEASE
+	}
+	if interval <= 0 {
+		cli.closeFile(ctx, session)
+		return nil, fmt.Errorf("%s: %w: lease time %v", OPEN_FILE, rpcerr.ErrInvalidParam, session.leaseTime)
+	}
+	go session.refreshLoop(interval)
+	return session, nil
+}
+
+func (s *Session) Id() string {
+	return s.sessionId
+}
+
+// FileInfo returns the file info got by the last refresh
+func (s *Session) FileInfo() FileInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fileInfo
+}
+
+// Lost receives the error once the session is lost, such as the file is
+// deleted or the lease expires without a successful refresh. The session
+// stops refreshing then, but Close should still be called.
+func (s *Session) Lost() <-chan error {
+	return s.lost
+}
+
+func (s *Session) refreshLoop(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastRefresh := time.Now()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := s.refresh(ctx)
+		cancel()
+		if err == nil {
+			lastRefresh = time.Now()
+			continue
+		}
+		if isSessionGone(err) {
+			s.lost <- err
+			return
+		}
+		// other errors such as kStorageError may be transient, keep refreshing until the lease expires
+		if time.Since(lastRefresh) >= s.leaseTime {
+			s.lost <- fmt.Errorf("%w: session %s of %s: %v", rpcerr.ErrLeaseExpired, s.sessionId, s.filename, err)
+			return
+		}
+	}
+}
+
+// mds answered that the session can not be refreshed any more
+func isSessionGone(err error) bool {
+	return errors.Is(err, rpcerr.ErrSessionNotExist) || errors.Is(err, rpcerr.ErrFileNotExists) ||
+		errors.Is(err, rpcerr.ErrOwnerAuthFail)
+}
+
+func (s *Session) refresh(ctx context.Context) error {
+	sig, date := s.option.Credential.Sign()
+	request := &nameserver2.ReFreshSessionRequest{
+		SessionID: &s.sessionId,
+		FileName:  &s.filename,
+		Owner:     &s.option.Credential.Owner,
+		Date:      &date,
+	}
+	if sig != "" {
+		request.Signature = &sig
+	}
+	if s.option.ClientIp != "" {
+		request.ClientIP = &s.option.ClientIp
+		request.ClientPort = &s.option.ClientPort
+	}
+	if s.option.ClientVersion != "" {
+		request.ClientVersion = &s.option.ClientVersion
+	}
+	response, err := callNameServer(ctx, s.cli, baserpc.NewRpcContext(s.cli.addrs, REFRESH_SESSION),
+		nameserver2.CurveFSServiceClient.RefreshSession, request)
+	if err != nil {
+		return err
+	}
+	if response.GetFileInfo() != nil {
+		s.mu.Lock()
+		s.fileInfo = getFileInfo(response.GetFileInfo())
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// Close stops refreshing and closes the file, it is safe to call more than once.
+func (s *Session) Close(ctx context.Context) error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+		s.closeErr = s.cli.closeFile(ctx, s)
+	})
+	return s.closeErr
+}
+
+func (cli *MdsClient) closeFile(ctx context.Context, s *Session) error {
+	sig, date := s.option.Credential.Sign()
+	request := &nameserver2.CloseFileRequest{
+		FileName:  &s.filename,
+		Owner:     &s.option.Credential.Owner,
+		SessionID: &s.sessionId,
+		Date:      &date,
+	}
+	if sig != "" {
+		request.Signature = &sig
+	}
+	if s.option.ClientIp != "" {
+		request.ClientIP = &s.option.ClientIp
+		request.ClientPort = &s.option.ClientPort
+	}
+	_, err := callNameServer(ctx, cli, baserpc.NewRpcContext(cli.addrs, CLOSE_FILE),
+		nameserver2.CurveFSServiceClient.CloseFile, request)
+	return err
+}
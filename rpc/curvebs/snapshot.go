@@ -0,0 +1,167 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"context"
+
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/nameserver2"
+	"github.com/SeanHai/curve-go-rpc/rpc/baserpc"
+)
+
+const (
+	// apis
+	CREATE_SNAPSHOT       = "CreateSnapShot"
+	LIST_SNAPSHOT         = "ListSnapShot"
+	GET_SNAPSHOT_FILEINFO = "GetSnapShotFileInfo"
+	CHECK_SNAPSHOT_STATUS = "CheckSnapShotStatus"
+	DELETE_SNAPSHOT       = "DeleteSnapShot"
+)
+
+// SnapShotStatus is the progress of a snapshot which is being deleted,
+// FileStatus is one of FILE_CREATED/FILE_DELETING/...
+type SnapShotStatus struct {
+	FileStatus string `json:"fileStatus"`
+	Progress   uint32 `json:"progress"`
+}
+
+// create snapshot of file, the SeqNum of returned snapshot identifies it
+func (cli *MdsClient) CreateSnapShot(filename, owner, sig string, date uint64) (FileInfo, error) {
+	return cli.CreateSnapShotWithContext(context.Background(), filename, owner, sig, date)
+}
+
+func (cli *MdsClient) CreateSnapShotWithContext(ctx context.Context, filename, owner, sig string, date uint64) (FileInfo, error) {
+	request := &nameserver2.CreateSnapShotRequest{
+		FileName: &filename,
+		Owner:    &owner,
+		Date:     &date,
+	}
+	if sig != "" {
+		request.Signature = &sig
+	}
+	response, err := callNameServer(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, CREATE_SNAPSHOT),
+		nameserver2.CurveFSServiceClient.CreateSnapShot, request)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return getFileInfo(response.GetSnapShotFileInfo()), nil
+}
+
+// list snapshots of file whose seq is in seqs, all snapshots if seqs is empty
+func (cli *MdsClient) ListSnapShot(filename, owner, sig string, date uint64, seqs []uint64) ([]FileInfo, error) {
+	return cli.ListSnapShotWithContext(context.Background(), filename, owner, sig, date, seqs)
+}
+
+func (cli *MdsClient) ListSnapShotWithContext(ctx context.Context, filename, owner, sig string, date uint64,
+	seqs []uint64) ([]FileInfo, error) {
+	request := &nameserver2.ListSnapShotFileInfoRequest{
+		FileName: &filename,
+		Owner:    &owner,
+		Date:     &date,
+		Seq:      seqs,
+	}
+	if sig != "" {
+		request.Signature = &sig
+	}
+	response, err := callNameServer(ctx, cli, baserpc.NewRpcContext(cli.addrs, LIST_SNAPSHOT),
+		nameserver2.CurveFSServiceClient.ListSnapShot, request)
+	if err != nil {
+		return nil, err
+	}
+	infos := []FileInfo{}
+	for _, v := range response.GetFileInfo() {
+		infos = append(infos, getFileInfo(v))
+	}
+	return infos, nil
+}
+
+func (cli *MdsClient) GetSnapShotFileInfo(filename, owner, sig string, seq, date uint64) (FileInfo, error) {
+	return cli.GetSnapShotFileInfoWithContext(context.Background(), filename, owner, sig, seq, date)
+}
+
+func (cli *MdsClient) GetSnapShotFileInfoWithContext(ctx context.Context, filename, owner, sig string,
+	seq, date uint64) (FileInfo, error) {
+	request := &nameserver2.GetSnapShotFileInfoRequest{
+		FileName: &filename,
+		Seq:      &seq,
+		Owner:    &owner,
+		Date:     &date,
+	}
+	if sig != "" {
+		request.Signature = &sig
+	}
+	response, err := callNameServer(ctx, cli, baserpc.NewRpcContext(cli.addrs, GET_SNAPSHOT_FILEINFO),
+		nameserver2.CurveFSServiceClient.GetSnapShotFileInfo, request)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return getFileInfo(response.GetSnapShotFileInfo()), nil
+}
+
+// check the progress of snapshot, rpcerr.ErrSnapshotNotExists is returned
+// once a deleting snapshot is gone.
+func (cli *MdsClient) CheckSnapShotStatus(filename, owner, sig string, seq, date uint64) (SnapShotStatus, error) {
+	return cli.CheckSnapShotStatusWithContext(context.Background(), filename, owner, sig, seq, date)
+}
+
+func (cli *MdsClient) CheckSnapShotStatusWithContext(ctx context.Context, filename, owner, sig string,
+	seq, date uint64) (SnapShotStatus, error) {
+	request := &nameserver2.CheckSnapShotStatusRequest{
+		FileName: &filename,
+		Seq:      &seq,
+		Owner:    &owner,
+		Date:     &date,
+	}
+	if sig != "" {
+		request.Signature = &sig
+	}
+	response, err := callNameServer(ctx, cli, baserpc.NewRpcContext(cli.addrs, CHECK_SNAPSHOT_STATUS),
+		nameserver2.CurveFSServiceClient.CheckSnapShotStatus, request)
+	if err != nil {
+		return SnapShotStatus{}, err
+	}
+	return SnapShotStatus{
+		FileStatus: getFileStatus(response.GetFileStatus()),
+		Progress:   response.GetProgress(),
+	}, nil
+}
+
+// delete snapshot asynchronously, use CheckSnapShotStatus to wait for it
+func (cli *MdsClient) DeleteSnapShot(filename, owner, sig string, seq, date uint64) error {
+	return cli.DeleteSnapShotWithContext(context.Background(), filename, owner, sig, seq, date)
+}
+
+func (cli *MdsClient) DeleteSnapShotWithContext(ctx context.Context, filename, owner, sig string, seq, date uint64) error {
+	request := &nameserver2.DeleteSnapShotRequest{
+		FileName: &filename,
+		Seq:      &seq,
+		Owner:    &owner,
+		Date:     &date,
+	}
+	if sig != "" {
+		request.Signature = &sig
+	}
+	_, err := callNameServer(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, DELETE_SNAPSHOT),
+		nameserver2.CurveFSServiceClient.DeleteSnapShot, request)
+	return err
+}
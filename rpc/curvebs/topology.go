@@ -23,14 +23,17 @@
 package curvebs
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"time"
 
+	pbcommon "github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/common"
 	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/topology"
-	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/topology/statuscode"
 	"github.com/SeanHai/curve-go-rpc/rpc/baserpc"
 	"github.com/SeanHai/curve-go-rpc/rpc/common"
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
 )
 
 const (
@@ -71,8 +74,36 @@ const (
 	GET_CHUNKSERVER_LIST_IN_COPYSETS = "GetChunkServerListInCopySets"
 	GET_COPYSETS_IN_CLUSTER          = "GetCopySetsInCluster"
 	GET_LOGICAL_POOL                 = "GetLogicalPool"
+	CREATE_PHYSICAL_POOL             = "CreatePhysicalPool"
+	DELETE_PHYSICAL_POOL             = "DeletePhysicalPool"
+	GET_PHYSICAL_POOL                = "GetPhysicalPool"
+	CREATE_ZONE                      = "CreateZone"
+	DELETE_ZONE                      = "DeleteZone"
+	GET_ZONE                         = "GetZone"
+	REGIST_SERVER                    = "RegistServer"
+	DELETE_SERVER                    = "DeleteServer"
+	GET_SERVER                       = "GetServer"
+	CREATE_LOGICAL_POOL              = "CreateLogicalPool"
+	DELETE_LOGICAL_POOL              = "DeleteLogicalPool"
+	SET_LOGICAL_POOL                 = "SetLogicalPool"
+	SET_LOGICAL_POOL_SCAN_STATE      = "SetLogicalPoolScanState"
+	REGIST_CHUNKSERVER               = "RegistChunkServer"
+	SET_CHUNKSERVER                  = "SetChunkServer"
+	DELETE_CHUNKSERVER               = "DeleteChunkServer"
+	GET_CHUNKSERVER                  = "GetChunkServer"
+	GET_COPYSET                      = "GetCopyset"
+	LIST_UNAVAIL_COPYSETS            = "ListUnAvailCopySets"
+	SET_COPYSETS_AVAIL_FLAG          = "SetCopysetsAvailFlag"
+	GET_CLUSTER_INFO                 = "GetClusterInfo"
+
+	// user policy of logical pool used by curve_ops_tool
+	DEFAULT_LOGICAL_POOL_USER_POLICY = `{"policy" : 1}`
 )
 
+type ClusterInfo struct {
+	ClusterId string `json:"clusterId" binding:"required"`
+}
+
 type PhysicalPool struct {
 	Id   uint32 `json:"id" binding:"required"`
 	Name string `json:"name" binding:"required"`
@@ -89,6 +120,27 @@ type LogicalPool struct {
 	ScanEnable     bool   `json:"scanEnable"`
 }
 
+// LogicalPoolOption describes the logical pool to create, Type is one of
+// PAGEFILE_TYPE/APPENDFILE_TYPE/APPENDECFILE_TYPE and AllocateStatus is one of
+// ALLOW_STATUS/DENY_STATUS, empty means ALLOW_STATUS.
+type LogicalPoolOption struct {
+	Name             string `json:"name" binding:"required"`
+	PhysicalPoolName string `json:"physicalPoolName" binding:"required"`
+	Type             string `json:"type" binding:"required"`
+	ReplicaNum       uint32 `json:"replicaNum" binding:"required"`
+	CopysetNum       uint32 `json:"copysetNum" binding:"required"`
+	ZoneNum          uint32 `json:"zoneNum" binding:"required"`
+	ScatterWidth     uint32 `json:"scatterWidth"`
+	AllocateStatus   string `json:"allocateStatus"`
+}
+
+// redundanceAndPlaceMentPolicy of logical pool
+type logicalPoolPolicy struct {
+	ReplicaNum uint32 `json:"replicaNum"`
+	CopysetNum uint32 `json:"copysetNum"`
+	ZoneNum    uint32 `json:"zoneNum"`
+}
+
 type Zone struct {
 	Id               uint32 `json:"id" binding:"required"`
 	Name             string `json:"name" binding:"required"`
@@ -120,9 +172,18 @@ type ChunkServer struct {
 	DiskStatus   string `json:"diskStatus" binding:"required"`
 	OnlineStatus string `json:"onlineStatus" binding:"required"`
 	MountPoint   string `json:"mountPoint" binding:"required"`
-	DiskCapacity string `json:"diskCapacity" binding:"required"`
-	DiskUsed     string `json:"diskUsed" binding:"required"`
+	DiskCapacity string `json:"diskCapacity" binding:"required"` // in whole GiB
+	DiskUsed     string `json:"diskUsed" binding:"required"`     // in whole GiB
 	ExternalIp   string `json:"externalIp"`
+	// exact sizes of disk in bytes
+	DiskCapacityBytes uint64 `json:"diskCapacityBytes"`
+	DiskUsedBytes     uint64 `json:"diskUsedBytes"`
+}
+
+// ListChunkServerOption controls which chunkservers are listed, RETIRED
+// chunkservers are skipped unless IncludeRetired.
+type ListChunkServerOption struct {
+	IncludeRetired bool
 }
 
 type CopySetInfo struct {
@@ -133,6 +194,15 @@ type CopySetInfo struct {
 	LastScanConsistent bool   `json:"lastScanConsistent"`
 }
 
+// CopySet is the copyset record kept by mds, Available is false if mds marked
+// it unavailable. The epoch and leader of copyset are not kept by mds, they
+// are only known by the chunkservers in Peers.
+type CopySet struct {
+	CopySetInfo
+	Peers     []ChunkServerLocation `json:"peers"`
+	Available bool                  `json:"available"`
+}
+
 type ChunkServerLocation struct {
 	ChunkServerId uint32 `json:"chunkServerId" binding:"required"`
 	HostIp        string `json:"hostIp" binding:"required"`
@@ -144,32 +214,96 @@ type CopySetServerInfo struct {
 	CsLocs    []ChunkServerLocation `json:"csLocs" binding:"required"`
 }
 
-func (cli *MdsClient) ListPhysicalPool() ([]PhysicalPool, error) {
-	Rpc := &ListPhysicalPoolRpc{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, LIST_PHYSICAL_POOL_FUNC)
-	Rpc.Request = &topology.ListPhysicalPoolRequest{}
+func (cli *MdsClient) GetClusterInfo() (ClusterInfo, error) {
+	return cli.GetClusterInfoWithContext(context.Background())
+}
 
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return nil, ret.Err
+func (cli *MdsClient) GetClusterInfoWithContext(ctx context.Context) (ClusterInfo, error) {
+	request := &topology.GetClusterInfoRequest{}
+	response, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, GET_CLUSTER_INFO),
+		topology.TopologyServiceClient.GetClusterInfo, request)
+	if err != nil {
+		return ClusterInfo{}, err
 	}
-	response := ret.Result.(*topology.ListPhysicalPoolResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != int32(statuscode.TopoStatusCode_Success) {
-		return nil, fmt.Errorf(statuscode.TopoStatusCode_name[statusCode])
+	return ClusterInfo{
+		ClusterId: response.GetClusterId(),
+	}, nil
+}
+
+func (cli *MdsClient) ListPhysicalPool() ([]PhysicalPool, error) {
+	return cli.ListPhysicalPoolWithContext(context.Background())
+}
+
+func (cli *MdsClient) ListPhysicalPoolWithContext(ctx context.Context) ([]PhysicalPool, error) {
+	request := &topology.ListPhysicalPoolRequest{}
+	response, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, LIST_PHYSICAL_POOL_FUNC),
+		topology.TopologyServiceClient.ListPhysicalPool, request)
+	if err != nil {
+		return nil, err
 	}
 
 	var infos []PhysicalPool
 	for _, pool := range response.GetPhysicalPoolInfos() {
-		info := PhysicalPool{}
-		info.Id = pool.GetPhysicalPoolID()
-		info.Name = pool.GetPhysicalPoolName()
-		info.Desc = pool.GetDesc()
-		infos = append(infos, info)
+		infos = append(infos, getPhysicalPool(pool))
 	}
 	return infos, nil
 }
 
+func getPhysicalPool(pool *topology.PhysicalPoolInfo) PhysicalPool {
+	info := PhysicalPool{}
+	info.Id = pool.GetPhysicalPoolID()
+	info.Name = pool.GetPhysicalPoolName()
+	info.Desc = pool.GetDesc()
+	return info
+}
+
+func (cli *MdsClient) CreatePhysicalPool(name, desc string) (PhysicalPool, error) {
+	return cli.CreatePhysicalPoolWithContext(context.Background(), name, desc)
+}
+
+func (cli *MdsClient) CreatePhysicalPoolWithContext(ctx context.Context, name, desc string) (PhysicalPool, error) {
+	request := &topology.PhysicalPoolRequest{
+		PhysicalPoolName: &name,
+		Desc:             &desc,
+	}
+	response, err := callTopology(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, CREATE_PHYSICAL_POOL),
+		topology.TopologyServiceClient.CreatePhysicalPool, request)
+	if err != nil {
+		return PhysicalPool{}, err
+	}
+	return getPhysicalPool(response.GetPhysicalPoolInfo()), nil
+}
+
+// the physical pool must have no zones
+func (cli *MdsClient) DeletePhysicalPool(poolId uint32) error {
+	return cli.DeletePhysicalPoolWithContext(context.Background(), poolId)
+}
+
+func (cli *MdsClient) DeletePhysicalPoolWithContext(ctx context.Context, poolId uint32) error {
+	request := &topology.PhysicalPoolRequest{
+		PhysicalPoolID: &poolId,
+	}
+	_, err := callTopology(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, DELETE_PHYSICAL_POOL),
+		topology.TopologyServiceClient.DeletePhysicalPool, request)
+	return err
+}
+
+func (cli *MdsClient) GetPhysicalPool(poolId uint32) (PhysicalPool, error) {
+	return cli.GetPhysicalPoolWithContext(context.Background(), poolId)
+}
+
+func (cli *MdsClient) GetPhysicalPoolWithContext(ctx context.Context, poolId uint32) (PhysicalPool, error) {
+	request := &topology.PhysicalPoolRequest{
+		PhysicalPoolID: &poolId,
+	}
+	response, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, GET_PHYSICAL_POOL),
+		topology.TopologyServiceClient.GetPhysicalPool, request)
+	if err != nil {
+		return PhysicalPool{}, err
+	}
+	return getPhysicalPool(response.GetPhysicalPoolInfo()), nil
+}
+
 func getLogicalPoolType(t topology.LogicalPoolType) string {
 	switch t {
 	case topology.LogicalPoolType_PAGEFILE:
@@ -194,9 +328,49 @@ func getLogicalPoolAllocateStatus(s topology.AllocateStatus) string {
 	}
 }
 
+func parseLogicalPoolType(t string) (topology.LogicalPoolType, error) {
+	switch t {
+	case PAGEFILE_TYPE:
+		return topology.LogicalPoolType_PAGEFILE, nil
+	case APPENDFILE_TYPE:
+		return topology.LogicalPoolType_APPENDFILE, nil
+	case APPENDECFILE_TYPE:
+		return topology.LogicalPoolType_APPENDECFILE, nil
+	default:
+		return 0, fmt.Errorf("%w: logical pool type %s", rpcerr.ErrInvalidParam, t)
+	}
+}
+
+func parseLogicalPoolAllocateStatus(s string) (topology.AllocateStatus, error) {
+	switch s {
+	case ALLOW_STATUS:
+		return topology.AllocateStatus_ALLOW, nil
+	case DENY_STATUS:
+		return topology.AllocateStatus_DENY, nil
+	default:
+		return 0, fmt.Errorf("%w: logical pool allocate status %s", rpcerr.ErrInvalidParam, s)
+	}
+}
+
+func getLogicalPool(pool *topology.LogicalPoolInfo) LogicalPool {
+	info := LogicalPool{}
+	info.Id = pool.GetLogicalPoolID()
+	info.Name = pool.GetLogicalPoolName()
+	info.PhysicalPoolId = pool.GetPhysicalPoolID()
+	info.Type = getLogicalPoolType(pool.GetType())
+	info.CreateTime = time.Unix(int64(pool.GetCreateTime()), 0).Format(common.TIME_FORMAT)
+	info.AllocateStatus = getLogicalPoolAllocateStatus(pool.GetAllocateStatus())
+	info.ScanEnable = pool.GetScanEnable()
+	return info
+}
+
 func (cli *MdsClient) ListLogicalPool() ([]LogicalPool, error) {
+	return cli.ListLogicalPoolWithContext(context.Background())
+}
+
+func (cli *MdsClient) ListLogicalPoolWithContext(ctx context.Context) ([]LogicalPool, error) {
 	// list physical pool and get pool id
-	physicalPools, err := cli.ListPhysicalPool()
+	physicalPools, err := cli.ListPhysicalPoolWithContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -204,46 +378,26 @@ func (cli *MdsClient) ListLogicalPool() ([]LogicalPool, error) {
 	results := make(chan baserpc.RpcResult, size)
 	for _, pool := range physicalPools {
 		go func(id uint32) {
-			Rpc := &ListLogicalPoolRpc{}
-			Rpc.ctx = baserpc.NewRpcContext(cli.addrs, LIST_LOGICAL_POOL_FUNC)
-			Rpc.Request = &topology.ListLogicalPoolRequest{
-				PhysicalPoolID: &id,
-			}
-			ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-			if ret.Err != nil {
+			response, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, LIST_LOGICAL_POOL_FUNC),
+				topology.TopologyServiceClient.ListLogicalPool, &topology.ListLogicalPoolRequest{
+					PhysicalPoolID: &id,
+				})
+			if err != nil {
 				results <- baserpc.RpcResult{
 					Key:    id,
-					Err:    fmt.Errorf("%s: %v", ret.Key, ret.Err),
+					Err:    err,
 					Result: nil,
 				}
-			} else {
-				response := ret.Result.(*topology.ListLogicalPoolResponse)
-				statusCode := response.GetStatusCode()
-				if statusCode != int32(statuscode.TopoStatusCode_Success) {
-					results <- baserpc.RpcResult{
-						Key:    id,
-						Err:    fmt.Errorf("%s", statuscode.TopoStatusCode_name[statusCode]),
-						Result: nil,
-					}
-				} else {
-					var pools []LogicalPool
-					for _, pool := range response.GetLogicalPoolInfos() {
-						info := LogicalPool{}
-						info.Id = pool.GetLogicalPoolID()
-						info.Name = pool.GetLogicalPoolName()
-						info.PhysicalPoolId = pool.GetPhysicalPoolID()
-						info.Type = getLogicalPoolType(pool.GetType())
-						info.CreateTime = time.Unix(int64(pool.GetCreateTime()), 0).Format(common.TIME_FORMAT)
-						info.AllocateStatus = getLogicalPoolAllocateStatus(pool.GetAllocateStatus())
-						info.ScanEnable = pool.GetScanEnable()
-						pools = append(pools, info)
-					}
-					results <- baserpc.RpcResult{
-						Key:    id,
-						Err:    nil,
-						Result: &pools,
-					}
-				}
+				return
+			}
+			var pools []LogicalPool
+			for _, pool := range response.GetLogicalPoolInfos() {
+				pools = append(pools, getLogicalPool(pool))
+			}
+			results <- baserpc.RpcResult{
+				Key:    id,
+				Err:    nil,
+				Result: &pools,
 			}
 		}(pool.Id)
 	}
@@ -252,7 +406,7 @@ func (cli *MdsClient) ListLogicalPool() ([]LogicalPool, error) {
 	count := 0
 	for res := range results {
 		if res.Err != nil {
-			return nil, fmt.Errorf("physical pool id: %d; %v", res.Key, res.Err)
+			return nil, fmt.Errorf("physical pool id: %d; %w", res.Key, res.Err)
 		}
 		pools = append(pools, (*res.Result.(*[]LogicalPool))...)
 		count++
@@ -264,101 +418,288 @@ func (cli *MdsClient) ListLogicalPool() ([]LogicalPool, error) {
 }
 
 func (cli *MdsClient) GetLogicalPool(poolId uint32) (LogicalPool, error) {
-	info := LogicalPool{}
-	Rpc := &GetLogicalPool{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, GET_LOGICAL_POOL)
-	Rpc.Request = &topology.GetLogicalPoolRequest{
+	return cli.GetLogicalPoolWithContext(context.Background(), poolId)
+}
+
+func (cli *MdsClient) GetLogicalPoolWithContext(ctx context.Context, poolId uint32) (LogicalPool, error) {
+	request := &topology.GetLogicalPoolRequest{
 		LogicalPoolID: &poolId,
 	}
+	response, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, GET_LOGICAL_POOL),
+		topology.TopologyServiceClient.GetLogicalPool, request)
+	if err != nil {
+		return LogicalPool{}, err
+	}
+	return getLogicalPool(response.GetLogicalPoolInfo()), nil
+}
 
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return info, ret.Err
+func (cli *MdsClient) CreateLogicalPool(option LogicalPoolOption) (LogicalPool, error) {
+	return cli.CreateLogicalPoolWithContext(context.Background(), option)
+}
+
+func (cli *MdsClient) CreateLogicalPoolWithContext(ctx context.Context, option LogicalPoolOption) (LogicalPool, error) {
+	poolType, err := parseLogicalPoolType(option.Type)
+	if err != nil {
+		return LogicalPool{}, err
 	}
-	response := ret.Result.(*topology.GetLogicalPoolResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != int32(statuscode.TopoStatusCode_Success) {
-		return info, fmt.Errorf(statuscode.TopoStatusCode_name[statusCode])
+	status := topology.AllocateStatus_ALLOW
+	if option.AllocateStatus != "" {
+		status, err = parseLogicalPoolAllocateStatus(option.AllocateStatus)
+		if err != nil {
+			return LogicalPool{}, err
+		}
 	}
-	pool := response.GetLogicalPoolInfo()
-	info.Id = pool.GetLogicalPoolID()
-	info.Name = pool.GetLogicalPoolName()
-	info.PhysicalPoolId = pool.GetPhysicalPoolID()
-	info.Type = getLogicalPoolType(pool.GetType())
-	info.CreateTime = time.Unix(int64(pool.GetCreateTime()), 0).Format(common.TIME_FORMAT)
-	info.AllocateStatus = getLogicalPoolAllocateStatus(pool.GetAllocateStatus())
-	info.ScanEnable = pool.GetScanEnable()
-	return info, nil
+	policy, err := json.Marshal(logicalPoolPolicy{
+		ReplicaNum: option.ReplicaNum,
+		CopysetNum: option.CopysetNum,
+		ZoneNum:    option.ZoneNum,
+	})
+	if err != nil {
+		return LogicalPool{}, err
+	}
+	request := &topology.CreateLogicalPoolRequest{
+		LogicalPoolName:              &option.Name,
+		PhysicalPoolName:             &option.PhysicalPoolName,
+		Type:                         &poolType,
+		RedundanceAndPlaceMentPolicy: policy,
+		UserPolicy:                   []byte(DEFAULT_LOGICAL_POOL_USER_POLICY),
+		Status:                       &status,
+	}
+	if option.ScatterWidth != 0 {
+		request.ScatterWidth = &option.ScatterWidth
+	}
+	response, err := callTopology(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, CREATE_LOGICAL_POOL),
+		topology.TopologyServiceClient.CreateLogicalPool, request)
+	if err != nil {
+		return LogicalPool{}, err
+	}
+	return getLogicalPool(response.GetLogicalPoolInfo()), nil
+}
+
+func (cli *MdsClient) DeleteLogicalPool(poolId uint32) error {
+	return cli.DeleteLogicalPoolWithContext(context.Background(), poolId)
+}
+
+func (cli *MdsClient) DeleteLogicalPoolWithContext(ctx context.Context, poolId uint32) error {
+	request := &topology.DeleteLogicalPoolRequest{
+		LogicalPoolID: &poolId,
+	}
+	_, err := callTopology(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, DELETE_LOGICAL_POOL),
+		topology.TopologyServiceClient.DeleteLogicalPool, request)
+	return err
+}
+
+// set allocate status of logical pool, status is ALLOW_STATUS or DENY_STATUS
+func (cli *MdsClient) SetLogicalPool(poolId uint32, status string) error {
+	return cli.SetLogicalPoolWithContext(context.Background(), poolId, status)
+}
+
+func (cli *MdsClient) SetLogicalPoolWithContext(ctx context.Context, poolId uint32, status string) error {
+	allocateStatus, err := parseLogicalPoolAllocateStatus(status)
+	if err != nil {
+		return err
+	}
+	request := &topology.SetLogicalPoolRequest{
+		LogicalPoolID: &poolId,
+		Status:        &allocateStatus,
+	}
+	_, err = callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, SET_LOGICAL_POOL),
+		topology.TopologyServiceClient.SetLogicalPool, request)
+	return err
+}
+
+func (cli *MdsClient) SetLogicalPoolScanState(poolId uint32, scanEnable bool) error {
+	return cli.SetLogicalPoolScanStateWithContext(context.Background(), poolId, scanEnable)
+}
+
+func (cli *MdsClient) SetLogicalPoolScanStateWithContext(ctx context.Context, poolId uint32, scanEnable bool) error {
+	request := &topology.SetLogicalPoolScanStateRequest{
+		LogicalPoolID: &poolId,
+		ScanEnable:    &scanEnable,
+	}
+	_, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, SET_LOGICAL_POOL_SCAN_STATE),
+		topology.TopologyServiceClient.SetLogicalPoolScanState, request)
+	return err
 }
 
 // list zones of physical pool
 func (cli *MdsClient) ListPoolZone(poolId uint32) ([]Zone, error) {
-	Rpc := &ListPoolZonesRpc{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, LIST_POOL_ZONE_FUNC)
-	Rpc.Request = &topology.ListPoolZoneRequest{
-		PhysicalPoolID: &poolId,
-	}
+	return cli.ListPoolZoneWithContext(context.Background(), poolId)
+}
 
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return nil, ret.Err
+func (cli *MdsClient) ListPoolZoneWithContext(ctx context.Context, poolId uint32) ([]Zone, error) {
+	request := &topology.ListPoolZoneRequest{
+		PhysicalPoolID: &poolId,
 	}
-	response := ret.Result.(*topology.ListPoolZoneResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != int32(statuscode.TopoStatusCode_Success) {
-		return nil, fmt.Errorf(statuscode.TopoStatusCode_name[statusCode])
+	response, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, LIST_POOL_ZONE_FUNC),
+		topology.TopologyServiceClient.ListPoolZone, request)
+	if err != nil {
+		return nil, err
 	}
 
 	infos := []Zone{}
 	for _, zone := range response.GetZones() {
-		info := Zone{}
-		info.Id = zone.GetZoneID()
-		info.Name = zone.GetZoneName()
-		info.PhysicalPoolId = zone.GetPhysicalPoolID()
-		info.PhysicalPoolName = zone.GetPhysicalPoolName()
-		info.Desc = zone.GetDesc()
-		infos = append(infos, info)
+		infos = append(infos, getZone(zone))
 	}
 	return infos, nil
 }
 
-// list servers of zone
-func (cli *MdsClient) ListZoneServer(zoneId uint32) ([]Server, error) {
-	Rpc := &ListZoneServer{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, LIST_ZONE_SERVER_FUNC)
-	Rpc.Request = &topology.ListZoneServerRequest{
+func getZone(zone *topology.ZoneInfo) Zone {
+	info := Zone{}
+	info.Id = zone.GetZoneID()
+	info.Name = zone.GetZoneName()
+	info.PhysicalPoolId = zone.GetPhysicalPoolID()
+	info.PhysicalPoolName = zone.GetPhysicalPoolName()
+	info.Desc = zone.GetDesc()
+	return info
+}
+
+// create zone in physical pool
+func (cli *MdsClient) CreateZone(name, physicalPoolName, desc string) (Zone, error) {
+	return cli.CreateZoneWithContext(context.Background(), name, physicalPoolName, desc)
+}
+
+func (cli *MdsClient) CreateZoneWithContext(ctx context.Context, name, physicalPoolName, desc string) (Zone, error) {
+	request := &topology.ZoneRequest{
+		ZoneName:         &name,
+		PhysicalPoolName: &physicalPoolName,
+		Desc:             &desc,
+	}
+	response, err := callTopology(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, CREATE_ZONE),
+		topology.TopologyServiceClient.CreateZone, request)
+	if err != nil {
+		return Zone{}, err
+	}
+	return getZone(response.GetZoneInfo()), nil
+}
+
+// the zone must have no servers
+func (cli *MdsClient) DeleteZone(zoneId uint32) error {
+	return cli.DeleteZoneWithContext(context.Background(), zoneId)
+}
+
+func (cli *MdsClient) DeleteZoneWithContext(ctx context.Context, zoneId uint32) error {
+	request := &topology.ZoneRequest{
 		ZoneID: &zoneId,
 	}
+	_, err := callTopology(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, DELETE_ZONE),
+		topology.TopologyServiceClient.DeleteZone, request)
+	return err
+}
+
+func (cli *MdsClient) GetZone(zoneId uint32) (Zone, error) {
+	return cli.GetZoneWithContext(context.Background(), zoneId)
+}
 
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return nil, ret.Err
+func (cli *MdsClient) GetZoneWithContext(ctx context.Context, zoneId uint32) (Zone, error) {
+	request := &topology.ZoneRequest{
+		ZoneID: &zoneId,
 	}
-	response := ret.Result.(*topology.ListZoneServerResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != int32(statuscode.TopoStatusCode_Success) {
-		return nil, fmt.Errorf(statuscode.TopoStatusCode_name[statusCode])
+	response, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, GET_ZONE),
+		topology.TopologyServiceClient.GetZone, request)
+	if err != nil {
+		return Zone{}, err
+	}
+	return getZone(response.GetZoneInfo()), nil
+}
+
+// list servers of zone
+func (cli *MdsClient) ListZoneServer(zoneId uint32) ([]Server, error) {
+	return cli.ListZoneServerWithContext(context.Background(), zoneId)
+}
+
+func (cli *MdsClient) ListZoneServerWithContext(ctx context.Context, zoneId uint32) ([]Server, error) {
+	request := &topology.ListZoneServerRequest{
+		ZoneID: &zoneId,
+	}
+	response, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, LIST_ZONE_SERVER_FUNC),
+		topology.TopologyServiceClient.ListZoneServer, request)
+	if err != nil {
+		return nil, err
 	}
 
 	infos := []Server{}
 	for _, server := range response.GetServerInfo() {
-		info := Server{}
-		info.Id = server.GetServerID()
-		info.HostName = server.GetHostName()
-		info.InternalIp = server.GetInternalIp()
-		info.InternalPort = server.GetInternalPort()
-		info.ExternalIp = server.GetExternalIp()
-		info.ExternalPort = server.GetExternalPort()
-		info.ZoneId = server.GetZoneID()
-		info.ZoneName = server.GetZoneName()
-		info.PhysicalPoolId = server.GetPhysicalPoolID()
-		info.PhysicalPoolName = server.GetPhysicalPoolName()
-		info.Desc = server.GetDesc()
-		infos = append(infos, info)
+		infos = append(infos, getServer(server))
 	}
 	return infos, nil
 }
 
+func getServer(server *topology.ServerInfo) Server {
+	info := Server{}
+	info.Id = server.GetServerID()
+	info.HostName = server.GetHostName()
+	info.InternalIp = server.GetInternalIp()
+	info.InternalPort = server.GetInternalPort()
+	info.ExternalIp = server.GetExternalIp()
+	info.ExternalPort = server.GetExternalPort()
+	info.ZoneId = server.GetZoneID()
+	info.ZoneName = server.GetZoneName()
+	info.PhysicalPoolId = server.GetPhysicalPoolID()
+	info.PhysicalPoolName = server.GetPhysicalPoolName()
+	info.Desc = server.GetDesc()
+	return info
+}
+
+// regist server into the zone named server.ZoneName of physical pool named
+// server.PhysicalPoolName, ids in server are ignored and the id of new server is returned.
+func (cli *MdsClient) RegistServer(server Server) (uint32, error) {
+	return cli.RegistServerWithContext(context.Background(), server)
+}
+
+func (cli *MdsClient) RegistServerWithContext(ctx context.Context, server Server) (uint32, error) {
+	request := &topology.ServerRegistRequest{
+		HostName:         &server.HostName,
+		InternalIp:       &server.InternalIp,
+		ExternalIp:       &server.ExternalIp,
+		ZoneName:         &server.ZoneName,
+		PhysicalPoolName: &server.PhysicalPoolName,
+		Desc:             &server.Desc,
+	}
+	if server.InternalPort != 0 {
+		request.InternalPort = &server.InternalPort
+	}
+	if server.ExternalPort != 0 {
+		request.ExternalPort = &server.ExternalPort
+	}
+	response, err := callTopology(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, REGIST_SERVER),
+		topology.TopologyServiceClient.RegistServer, request)
+	if err != nil {
+		return 0, err
+	}
+	return response.GetServerID(), nil
+}
+
+// the server must have no chunkservers
+func (cli *MdsClient) DeleteServer(serverId uint32) error {
+	return cli.DeleteServerWithContext(context.Background(), serverId)
+}
+
+func (cli *MdsClient) DeleteServerWithContext(ctx context.Context, serverId uint32) error {
+	request := &topology.DeleteServerRequest{
+		ServerID: &serverId,
+	}
+	_, err := callTopology(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, DELETE_SERVER),
+		topology.TopologyServiceClient.DeleteServer, request)
+	return err
+}
+
+func (cli *MdsClient) GetServer(serverId uint32) (Server, error) {
+	return cli.GetServerWithContext(context.Background(), serverId)
+}
+
+func (cli *MdsClient) GetServerWithContext(ctx context.Context, serverId uint32) (Server, error) {
+	request := &topology.GetServerRequest{
+		ServerID: &serverId,
+	}
+	response, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, GET_SERVER),
+		topology.TopologyServiceClient.GetServer, request)
+	if err != nil {
+		return Server{}, err
+	}
+	return getServer(response.GetServerInfo()), nil
+}
+
 // list chunkservers of server
 func getChunkServerStatus(s topology.ChunkServerStatus) string {
 	switch s {
@@ -397,126 +738,211 @@ func getOnlineStatus(s topology.OnlineState) string {
 	}
 }
 
-func (cli *MdsClient) ListChunkServer(serverId uint32) ([]ChunkServer, error) {
-	Rpc := &ListChunkServer{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, LIST_CHUNKSERVER_FUNC)
-	Rpc.Request = &topology.ListChunkServerRequest{
-		ServerID: &serverId,
+func parseChunkServerStatus(s string) (topology.ChunkServerStatus, error) {
+	switch s {
+	case READWRITE_STATUS:
+		return topology.ChunkServerStatus_READWRITE, nil
+	case PENDDING_STATUS:
+		return topology.ChunkServerStatus_PENDDING, nil
+	case RETIRED_STATUS:
+		return topology.ChunkServerStatus_RETIRED, nil
+	default:
+		return 0, fmt.Errorf("%w: chunkserver status %s", rpcerr.ErrInvalidParam, s)
 	}
+}
 
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return nil, ret.Err
-	}
-	response := ret.Result.(*topology.ListChunkServerResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != int32(statuscode.TopoStatusCode_Success) {
-		return nil, fmt.Errorf(statuscode.TopoStatusCode_name[statusCode])
-	}
+func getChunkServer(cs *topology.ChunkServerInfo) ChunkServer {
+	info := ChunkServer{}
+	info.Id = cs.GetChunkServerID()
+	info.DiskType = cs.GetDiskType()
+	info.HostIp = cs.GetHostIp()
+	info.Port = cs.GetPort()
+	info.Status = getChunkServerStatus(cs.GetStatus())
+	info.DiskStatus = getDiskStatus(cs.GetDiskStatus())
+	info.OnlineStatus = getOnlineStatus(cs.GetOnlineState())
+	info.MountPoint = cs.GetMountPoint()
+	info.DiskCapacity = strconv.FormatUint(cs.GetDiskCapacity()/common.GiB, 10)
+	info.DiskUsed = strconv.FormatUint(cs.GetDiskUsed()/common.GiB, 10)
+	info.ExternalIp = cs.GetExternalIp()
+	info.DiskCapacityBytes = cs.GetDiskCapacity()
+	info.DiskUsedBytes = cs.GetDiskUsed()
+	return info
+}
 
+func getChunkServers(css []*topology.ChunkServerInfo, option []ListChunkServerOption) []ChunkServer {
+	includeRetired := len(option) > 0 && option[0].IncludeRetired
 	infos := []ChunkServer{}
-	for _, cs := range response.GetChunkServerInfos() {
-		if cs.GetStatus() == topology.ChunkServerStatus_RETIRED {
+	for _, cs := range css {
+		if cs.GetStatus() == topology.ChunkServerStatus_RETIRED && !includeRetired {
 			continue
 		}
-		info := ChunkServer{}
-		info.Id = cs.GetChunkServerID()
-		info.DiskType = cs.GetDiskType()
-		info.HostIp = cs.GetHostIp()
-		info.Port = cs.GetPort()
-		info.Status = getChunkServerStatus(cs.GetStatus())
-		info.DiskStatus = getDiskStatus(cs.GetDiskStatus())
-		info.OnlineStatus = getOnlineStatus(cs.GetOnlineState())
-		info.MountPoint = cs.GetMountPoint()
-		info.DiskCapacity = strconv.FormatUint(cs.GetDiskCapacity()/common.GiB, 10)
-		info.DiskUsed = strconv.FormatUint(cs.GetDiskUsed()/common.GiB, 10)
-		info.ExternalIp = cs.GetExternalIp()
-		infos = append(infos, info)
+		infos = append(infos, getChunkServer(cs))
 	}
-	return infos, nil
+	return infos
 }
 
-func (cli *MdsClient) GetChunkServerInCluster() ([]ChunkServer, error) {
-	Rpc := &GetChunkServerInCluster{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, GET_CHUNKSERVER_IN_CLUSTER_FUNC)
-	Rpc.Request = &topology.GetChunkServerInClusterRequest{}
+func (cli *MdsClient) ListChunkServer(serverId uint32, option ...ListChunkServerOption) ([]ChunkServer, error) {
+	return cli.ListChunkServerWithContext(context.Background(), serverId, option...)
+}
 
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return nil, ret.Err
+func (cli *MdsClient) ListChunkServerWithContext(ctx context.Context, serverId uint32,
+	option ...ListChunkServerOption) ([]ChunkServer, error) {
+	request := &topology.ListChunkServerRequest{
+		ServerID: &serverId,
 	}
-	response := ret.Result.(*topology.GetChunkServerInClusterResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != int32(statuscode.TopoStatusCode_Success) {
-		return nil, fmt.Errorf(statuscode.TopoStatusCode_name[statusCode])
+	response, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, LIST_CHUNKSERVER_FUNC),
+		topology.TopologyServiceClient.ListChunkServer, request)
+	if err != nil {
+		return nil, err
 	}
-	infos := []ChunkServer{}
-	for _, cs := range response.GetChunkServerInfos() {
-		if cs.GetStatus() == topology.ChunkServerStatus_RETIRED {
-			continue
-		}
-		info := ChunkServer{}
-		info.Id = cs.GetChunkServerID()
-		info.DiskType = cs.GetDiskType()
-		info.HostIp = cs.GetHostIp()
-		info.Port = cs.GetPort()
-		info.Status = getChunkServerStatus(cs.GetStatus())
-		info.DiskStatus = getDiskStatus(cs.GetDiskStatus())
-		info.OnlineStatus = getOnlineStatus(cs.GetOnlineState())
-		info.MountPoint = cs.GetMountPoint()
-		info.DiskCapacity = strconv.FormatUint(cs.GetDiskCapacity()/common.GiB, 10)
-		info.DiskUsed = strconv.FormatUint(cs.GetDiskUsed()/common.GiB, 10)
-		info.ExternalIp = cs.GetExternalIp()
-		infos = append(infos, info)
+	return getChunkServers(response.GetChunkServerInfos(), option), nil
+}
+
+func (cli *MdsClient) GetChunkServerInCluster(option ...ListChunkServerOption) ([]ChunkServer, error) {
+	return cli.GetChunkServerInClusterWithContext(context.Background(), option...)
+}
+
+func (cli *MdsClient) GetChunkServerInClusterWithContext(ctx context.Context,
+	option ...ListChunkServerOption) ([]ChunkServer, error) {
+	request := &topology.GetChunkServerInClusterRequest{}
+	response, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, GET_CHUNKSERVER_IN_CLUSTER_FUNC),
+		topology.TopologyServiceClient.GetChunkServerInCluster, request)
+	if err != nil {
+		return nil, err
 	}
-	return infos, nil
+	return getChunkServers(response.GetChunkServerInfos(), option), nil
 }
 
-func (cli *MdsClient) GetCopySetsInChunkServer(ip string, port uint32) ([]CopySetInfo, error) {
-	Rpc := &GetCopySetsInChunkServer{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, GET_COPYSET_IN_CHUNKSERVER_FUNC)
-	Rpc.Request = &topology.GetCopySetsInChunkServerRequest{
+// regist chunkserver with cs.DiskType, cs.MountPoint, cs.HostIp, cs.Port and
+// cs.ExternalIp, the id of new chunkserver is returned.
+func (cli *MdsClient) RegistChunkServer(cs ChunkServer) (uint32, error) {
+	return cli.RegistChunkServerWithContext(context.Background(), cs)
+}
+
+func (cli *MdsClient) RegistChunkServerWithContext(ctx context.Context, cs ChunkServer) (uint32, error) {
+	request := &topology.ChunkServerRegistRequest{
+		DiskType: &cs.DiskType,
+		DiskPath: &cs.MountPoint,
+		HostIp:   &cs.HostIp,
+		Port:     &cs.Port,
+	}
+	if cs.ExternalIp != "" {
+		request.ExternalIp = &cs.ExternalIp
+	}
+	response, err := callTopology(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, REGIST_CHUNKSERVER),
+		topology.TopologyServiceClient.RegistChunkServer, request)
+	if err != nil {
+		return 0, err
+	}
+	return response.GetChunkServerID(), nil
+}
+
+// set status of chunkserver, status is one of READWRITE_STATUS/PENDDING_STATUS/RETIRED_STATUS
+func (cli *MdsClient) SetChunkServer(chunkserverId uint32, status string) error {
+	return cli.SetChunkServerWithContext(context.Background(), chunkserverId, status)
+}
+
+func (cli *MdsClient) SetChunkServerWithContext(ctx context.Context, chunkserverId uint32, status string) error {
+	csStatus, err := parseChunkServerStatus(status)
+	if err != nil {
+		return err
+	}
+	request := &topology.SetChunkServerStatusRequest{
+		ChunkServerID:     &chunkserverId,
+		ChunkServerStatus: &csStatus,
+	}
+	_, err = callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, SET_CHUNKSERVER),
+		topology.TopologyServiceClient.SetChunkServer, request)
+	return err
+}
+
+// the chunkserver must be RETIRED and have no copysets
+func (cli *MdsClient) DeleteChunkServer(chunkserverId uint32) error {
+	return cli.DeleteChunkServerWithContext(context.Background(), chunkserverId)
+}
+
+func (cli *MdsClient) DeleteChunkServerWithContext(ctx context.Context, chunkserverId uint32) error {
+	request := &topology.DeleteChunkServerRequest{
+		ChunkServerID: &chunkserverId,
+	}
+	_, err := callTopology(ctx, cli, baserpc.NewNonIdempotentRpcContext(cli.addrs, DELETE_CHUNKSERVER),
+		topology.TopologyServiceClient.DeleteChunkServer, request)
+	return err
+}
+
+func (cli *MdsClient) GetChunkServer(chunkserverId uint32) (ChunkServer, error) {
+	return cli.GetChunkServerWithContext(context.Background(), chunkserverId)
+}
+
+func (cli *MdsClient) GetChunkServerWithContext(ctx context.Context, chunkserverId uint32) (ChunkServer, error) {
+	return cli.getChunkServer(ctx, &topology.GetChunkServerInfoRequest{
+		ChunkServerID: &chunkserverId,
+	})
+}
+
+func (cli *MdsClient) GetChunkServerByAddr(ip string, port uint32) (ChunkServer, error) {
+	return cli.GetChunkServerByAddrWithContext(context.Background(), ip, port)
+}
+
+func (cli *MdsClient) GetChunkServerByAddrWithContext(ctx context.Context, ip string, port uint32) (ChunkServer, error) {
+	return cli.getChunkServer(ctx, &topology.GetChunkServerInfoRequest{
 		HostIp: &ip,
 		Port:   &port,
+	})
+}
+
+func (cli *MdsClient) getChunkServer(ctx context.Context, request *topology.GetChunkServerInfoRequest) (ChunkServer, error) {
+	response, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, GET_CHUNKSERVER),
+		topology.TopologyServiceClient.GetChunkServer, request)
+	if err != nil {
+		return ChunkServer{}, err
 	}
+	return getChunkServer(response.GetChunkServerInfo()), nil
+}
 
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return nil, ret.Err
+func (cli *MdsClient) GetCopySetsInChunkServer(ip string, port uint32) ([]CopySetInfo, error) {
+	return cli.GetCopySetsInChunkServerWithContext(context.Background(), ip, port)
+}
+
+func (cli *MdsClient) GetCopySetsInChunkServerWithContext(ctx context.Context, ip string, port uint32) ([]CopySetInfo, error) {
+	request := &topology.GetCopySetsInChunkServerRequest{
+		HostIp: &ip,
+		Port:   &port,
 	}
-	response := ret.Result.(*topology.GetCopySetsInChunkServerResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != int32(statuscode.TopoStatusCode_Success) {
-		return nil, fmt.Errorf(statuscode.TopoStatusCode_name[statusCode])
+	response, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, GET_COPYSET_IN_CHUNKSERVER_FUNC),
+		topology.TopologyServiceClient.GetCopySetsInChunkServer, request)
+	if err != nil {
+		return nil, err
 	}
 	infos := []CopySetInfo{}
 	for _, cs := range response.GetCopysetInfos() {
-		info := CopySetInfo{}
-		info.LogicalPoolId = cs.GetLogicalPoolId()
-		info.CopysetId = cs.GetCopysetId()
-		info.Scanning = cs.GetScaning()
-		info.LastScanSec = cs.GetLastScanSec()
-		info.LastScanConsistent = cs.GetLastScanConsistent()
-		infos = append(infos, info)
+		infos = append(infos, getCopySetInfo(cs))
 	}
 	return infos, nil
 }
 
+func getCopySetInfo(cs *pbcommon.CopysetInfo) CopySetInfo {
+	info := CopySetInfo{}
+	info.LogicalPoolId = cs.GetLogicalPoolId()
+	info.CopysetId = cs.GetCopysetId()
+	info.Scanning = cs.GetScaning()
+	info.LastScanSec = cs.GetLastScanSec()
+	info.LastScanConsistent = cs.GetLastScanConsistent()
+	return info
+}
+
 func (cli *MdsClient) GetChunkServerListInCopySets(logicalPoolId uint32, copysetIds []uint32) ([]CopySetServerInfo, error) {
-	Rpc := &GetChunkServerListInCopySets{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, GET_CHUNKSERVER_LIST_IN_COPYSETS)
-	Rpc.Request = &topology.GetChunkServerListInCopySetsRequest{}
-	Rpc.Request.LogicalPoolId = &logicalPoolId
-	Rpc.Request.CopysetId = append(Rpc.Request.CopysetId, copysetIds...)
+	return cli.GetChunkServerListInCopySetsWithContext(context.Background(), logicalPoolId, copysetIds)
+}
 
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return nil, ret.Err
-	}
-	response := ret.Result.(*topology.GetChunkServerListInCopySetsResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != int32(statuscode.TopoStatusCode_Success) {
-		return nil, fmt.Errorf(statuscode.TopoStatusCode_name[statusCode])
+func (cli *MdsClient) GetChunkServerListInCopySetsWithContext(ctx context.Context, logicalPoolId uint32, copysetIds []uint32) ([]CopySetServerInfo, error) {
+	request := &topology.GetChunkServerListInCopySetsRequest{}
+	request.LogicalPoolId = &logicalPoolId
+	request.CopysetId = append(request.CopysetId, copysetIds...)
+	response, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, GET_CHUNKSERVER_LIST_IN_COPYSETS),
+		topology.TopologyServiceClient.GetChunkServerListInCopySets, request)
+	if err != nil {
+		return nil, err
 	}
 	infos := []CopySetServerInfo{}
 	for _, csInfo := range response.GetCsInfo() {
@@ -536,28 +962,131 @@ func (cli *MdsClient) GetChunkServerListInCopySets(logicalPoolId uint32, copyset
 }
 
 func (cli *MdsClient) GetCopySetsInCluster() ([]CopySetInfo, error) {
-	Rpc := &GetCopySetsInCluster{}
-	Rpc.ctx = baserpc.NewRpcContext(cli.addrs, GET_COPYSETS_IN_CLUSTER)
-	Rpc.Request = &topology.GetCopySetsInClusterRequest{}
+	return cli.GetCopySetsInClusterWithContext(context.Background())
+}
 
-	ret := cli.baseClient.SendRpc(Rpc.ctx, Rpc)
-	if ret.Err != nil {
-		return nil, ret.Err
-	}
-	response := ret.Result.(*topology.GetCopySetsInClusterResponse)
-	statusCode := response.GetStatusCode()
-	if statusCode != int32(statuscode.TopoStatusCode_Success) {
-		return nil, fmt.Errorf(statuscode.TopoStatusCode_name[statusCode])
+func (cli *MdsClient) GetCopySetsInClusterWithContext(ctx context.Context) ([]CopySetInfo, error) {
+	request := &topology.GetCopySetsInClusterRequest{}
+	response, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, GET_COPYSETS_IN_CLUSTER),
+		topology.TopologyServiceClient.GetCopySetsInCluster, request)
+	if err != nil {
+		return nil, err
 	}
 	infos := []CopySetInfo{}
 	for _, csInfo := range response.GetCopysetInfos() {
-		info := CopySetInfo{}
-		info.CopysetId = csInfo.GetCopysetId()
-		info.LastScanConsistent = csInfo.GetLastScanConsistent()
-		info.LastScanSec = csInfo.GetLastScanSec()
-		info.LogicalPoolId = csInfo.GetLogicalPoolId()
-		info.Scanning = csInfo.GetScaning()
-		infos = append(infos, info)
+		infos = append(infos, getCopySetInfo(csInfo))
 	}
 	return infos, nil
 }
+
+// fill peers of copysets which may be in different logical pools
+func (cli *MdsClient) fillCopySetPeers(ctx context.Context, copysets []CopySet) error {
+	pools := make(map[uint32][]uint32)
+	for _, cs := range copysets {
+		pools[cs.LogicalPoolId] = append(pools[cs.LogicalPoolId], cs.CopysetId)
+	}
+	type copysetKey struct {
+		logicalPoolId uint32
+		copysetId     uint32
+	}
+	peers := make(map[copysetKey][]ChunkServerLocation)
+	for poolId, copysetIds := range pools {
+		infos, err := cli.GetChunkServerListInCopySetsWithContext(ctx, poolId, copysetIds)
+		if err != nil {
+			return fmt.Errorf("logical pool id: %d; %w", poolId, err)
+		}
+		for _, info := range infos {
+			peers[copysetKey{poolId, info.CopysetId}] = info.CsLocs
+		}
+	}
+	for i := range copysets {
+		copysets[i].Peers = peers[copysetKey{copysets[i].LogicalPoolId, copysets[i].CopysetId}]
+	}
+	return nil
+}
+
+// list copysets marked unavailable by mds
+func (cli *MdsClient) ListUnAvailCopySets() ([]CopySet, error) {
+	return cli.ListUnAvailCopySetsWithContext(context.Background())
+}
+
+func (cli *MdsClient) ListUnAvailCopySetsWithContext(ctx context.Context) ([]CopySet, error) {
+	request := &topology.ListUnAvailCopySetsRequest{}
+	response, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, LIST_UNAVAIL_COPYSETS),
+		topology.TopologyServiceClient.ListUnAvailCopySets, request)
+	if err != nil {
+		return nil, err
+	}
+	copysets := []CopySet{}
+	for _, cs := range response.GetCopysets() {
+		copysets = append(copysets, CopySet{
+			CopySetInfo: getCopySetInfo(cs),
+			Available:   false,
+		})
+	}
+	if len(copysets) == 0 {
+		return copysets, nil
+	}
+	if err := cli.fillCopySetPeers(ctx, copysets); err != nil {
+		return nil, err
+	}
+	return copysets, nil
+}
+
+// mark copysets available or not, only LogicalPoolId and CopysetId of copysets are used
+func (cli *MdsClient) SetCopysetsAvailFlag(copysets []CopySetInfo, available bool) error {
+	return cli.SetCopysetsAvailFlagWithContext(context.Background(), copysets, available)
+}
+
+func (cli *MdsClient) SetCopysetsAvailFlagWithContext(ctx context.Context, copysets []CopySetInfo, available bool) error {
+	request := &topology.SetCopysetsAvailFlagRequest{
+		AvailFlag: &available,
+	}
+	for i := range copysets {
+		request.Copysets = append(request.Copysets, &pbcommon.CopysetInfo{
+			LogicalPoolId: &copysets[i].LogicalPoolId,
+			CopysetId:     &copysets[i].CopysetId,
+		})
+	}
+	_, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, SET_COPYSETS_AVAIL_FLAG),
+		topology.TopologyServiceClient.SetCopysetsAvailFlag, request)
+	return err
+}
+
+// get copyset with its peers and availability
+func (cli *MdsClient) GetCopyset(logicalPoolId, copysetId uint32) (CopySet, error) {
+	return cli.GetCopysetWithContext(context.Background(), logicalPoolId, copysetId)
+}
+
+func (cli *MdsClient) GetCopysetWithContext(ctx context.Context, logicalPoolId, copysetId uint32) (CopySet, error) {
+	request := &topology.GetCopysetRequest{
+		LogicalPoolId: &logicalPoolId,
+		CopysetId:     &copysetId,
+	}
+	response, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, GET_COPYSET),
+		topology.TopologyServiceClient.GetCopyset, request)
+	if err != nil {
+		return CopySet{}, err
+	}
+	copysets := []CopySet{{
+		CopySetInfo: getCopySetInfo(response.GetCopysetInfo()),
+		Available:   true,
+	}}
+	if err := cli.fillCopySetPeers(ctx, copysets); err != nil {
+		return CopySet{}, err
+	}
+
+	// only the requested copyset is checked, peers of the others are not needed
+	unavailable, err := callTopology(ctx, cli, baserpc.NewRpcContext(cli.addrs, LIST_UNAVAIL_COPYSETS),
+		topology.TopologyServiceClient.ListUnAvailCopySets, &topology.ListUnAvailCopySetsRequest{})
+	if err != nil {
+		return CopySet{}, err
+	}
+	for _, cs := range unavailable.GetCopysets() {
+		if cs.GetLogicalPoolId() == logicalPoolId && cs.GetCopysetId() == copysetId {
+			copysets[0].Available = false
+			break
+		}
+	}
+	return copysets[0], nil
+}
@@ -24,12 +24,19 @@ package curvebs
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"testing"
 
+	pbcommon "github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/common"
 	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/topology"
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/topology/statuscode"
+	"github.com/SeanHai/curve-go-rpc/rpc/baserpc"
+	"github.com/SeanHai/curve-go-rpc/rpc/common"
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
@@ -41,9 +48,33 @@ const (
 var (
 	gs *grpc.Server
 
-	status_success     int32  = 0
-	physical_pool_id   uint32 = 1
-	physical_pool_name string = "physical_pool"
+	status_success                int32  = 0
+	status_pool_not_found         int32  = int32(statuscode.TopoStatusCode_PhysicalPoolNotFound)
+	status_zone_not_found         int32  = int32(statuscode.TopoStatusCode_ZoneNotFound)
+	status_server_not_found       int32  = int32(statuscode.TopoStatusCode_ServerNotFound)
+	status_chunkserver_not_found  int32  = int32(statuscode.TopoStatusCode_ChunkServerNotFound)
+	status_copyset_not_found      int32  = int32(statuscode.TopoStatusCode_CopySetNotFound)
+	status_invalid_param          int32  = int32(statuscode.TopoStatusCode_InvalidParam)
+	status_logical_pool_not_found int32  = int32(statuscode.TopoStatusCode_LogicalPoolNotFound)
+	cluster_id                    string = "9f8b5c2e-4d6a-4b1e-8c3f-1a2b3c4d5e6f"
+	physical_pool_id              uint32 = 1
+	physical_pool_name            string = "physical_pool"
+	logical_pool_id               uint32 = 4
+	logical_pool_name             string = "logical_pool"
+	copyset_id                    uint32 = 6
+	unavail_copyset_id            uint32 = 7
+	zone_id                       uint32 = 2
+	zone_name                     string = "zone"
+	server_id                     uint32 = 3
+	server_host_name              string = "server"
+	server_ip                     string = "127.0.0.1"
+	server_port                   uint32 = 8200
+	chunkserver_id                uint32 = 5
+	chunkserver_port              uint32 = 8201
+	disk_type                     string = "nvme"
+	mount_point                   string = "/data/chunkserver0"
+	readwrite                            = topology.ChunkServerStatus_READWRITE
+	retired                              = topology.ChunkServerStatus_RETIRED
 
 	clientOption MdsClientOption = MdsClientOption{
 		TimeoutMs:  500,
@@ -58,17 +89,52 @@ type server struct {
 
 func (s *server) CreateLogicalPool(ctx context.Context, req *topology.CreateLogicalPoolRequest) (
 	*topology.CreateLogicalPoolResponse, error) {
-	return nil, nil
+	var policy logicalPoolPolicy
+	if err := json.Unmarshal(req.GetRedundanceAndPlaceMentPolicy(), &policy); err != nil || policy.ReplicaNum == 0 {
+		return &topology.CreateLogicalPoolResponse{StatusCode: &status_invalid_param}, nil
+	}
+	return &topology.CreateLogicalPoolResponse{
+		StatusCode: &status_success,
+		LogicalPoolInfo: &topology.LogicalPoolInfo{
+			LogicalPoolID:                &logical_pool_id,
+			LogicalPoolName:              req.LogicalPoolName,
+			PhysicalPoolID:               &physical_pool_id,
+			Type:                         req.Type,
+			CreateTime:                   new(uint64),
+			RedundanceAndPlaceMentPolicy: req.RedundanceAndPlaceMentPolicy,
+			UserPolicy:                   req.UserPolicy,
+			AllocateStatus:               req.Status,
+		},
+	}, nil
 }
 
 func (s *server) CreatePhysicalPool(ctx context.Context, req *topology.PhysicalPoolRequest) (
 	*topology.PhysicalPoolResponse, error) {
-	return nil, nil
+	return &topology.PhysicalPoolResponse{
+		StatusCode: &status_success,
+		PhysicalPoolInfo: &topology.PhysicalPoolInfo{
+			PhysicalPoolID:   &physical_pool_id,
+			PhysicalPoolName: req.PhysicalPoolName,
+			Desc:             req.Desc,
+		},
+	}, nil
 }
 
 func (s *server) CreateZone(ctx context.Context, req *topology.ZoneRequest) (
 	*topology.ZoneResponse, error) {
-	return nil, nil
+	if req.GetPhysicalPoolName() != physical_pool_name {
+		return &topology.ZoneResponse{StatusCode: &status_pool_not_found}, nil
+	}
+	return &topology.ZoneResponse{
+		StatusCode: &status_success,
+		ZoneInfo: &topology.ZoneInfo{
+			ZoneID:           &zone_id,
+			ZoneName:         req.ZoneName,
+			PhysicalPoolID:   &physical_pool_id,
+			PhysicalPoolName: req.PhysicalPoolName,
+			Desc:             req.Desc,
+		},
+	}, nil
 }
 
 func (s *server) DeleteChunkServer(ctx context.Context, req *topology.DeleteChunkServerRequest) (
@@ -83,22 +149,37 @@ func (s *server) DeleteLogicalPool(ctx context.Context, req *topology.DeleteLogi
 
 func (s *server) DeletePhysicalPool(ctx context.Context, req *topology.PhysicalPoolRequest) (
 	*topology.PhysicalPoolResponse, error) {
-	return nil, nil
+	if req.GetPhysicalPoolID() != physical_pool_id {
+		return &topology.PhysicalPoolResponse{StatusCode: &status_pool_not_found}, nil
+	}
+	return &topology.PhysicalPoolResponse{StatusCode: &status_success}, nil
 }
 
 func (s *server) DeleteServer(ctx context.Context, req *topology.DeleteServerRequest) (
 	*topology.DeleteServerResponse, error) {
-	return nil, nil
+	if req.GetServerID() != server_id {
+		return &topology.DeleteServerResponse{StatusCode: &status_server_not_found}, nil
+	}
+	return &topology.DeleteServerResponse{StatusCode: &status_success}, nil
 }
 
 func (s *server) DeleteZone(ctx context.Context, req *topology.ZoneRequest) (
 	*topology.ZoneResponse, error) {
-	return nil, nil
+	if req.GetZoneID() != zone_id {
+		return &topology.ZoneResponse{StatusCode: &status_zone_not_found}, nil
+	}
+	return &topology.ZoneResponse{StatusCode: &status_success}, nil
 }
 
 func (s *server) GetChunkServer(ctx context.Context, req *topology.GetChunkServerInfoRequest) (
 	*topology.GetChunkServerInfoResponse, error) {
-	return nil, nil
+	if req.GetChunkServerID() != chunkserver_id && (req.GetHostIp() != server_ip || req.GetPort() != chunkserver_port) {
+		return &topology.GetChunkServerInfoResponse{StatusCode: &status_chunkserver_not_found}, nil
+	}
+	return &topology.GetChunkServerInfoResponse{
+		StatusCode:      &status_success,
+		ChunkServerInfo: newChunkServerInfo(chunkserver_id, readwrite),
+	}, nil
 }
 
 func (s *server) GetChunkServerInCluster(ctx context.Context, req *topology.GetChunkServerInClusterRequest) (
@@ -108,12 +189,26 @@ func (s *server) GetChunkServerInCluster(ctx context.Context, req *topology.GetC
 
 func (s *server) GetChunkServerListInCopySets(ctx context.Context, req *topology.GetChunkServerListInCopySetsRequest) (
 	*topology.GetChunkServerListInCopySetsResponse, error) {
-	return nil, nil
+	response := &topology.GetChunkServerListInCopySetsResponse{StatusCode: &status_success}
+	for i := range req.GetCopysetId() {
+		response.CsInfo = append(response.CsInfo, &topology.CopySetServerInfo{
+			CopysetId: &req.CopysetId[i],
+			CsLocs: []*pbcommon.ChunkServerLocation{{
+				ChunkServerID: &chunkserver_id,
+				HostIp:        &server_ip,
+				Port:          &chunkserver_port,
+			}},
+		})
+	}
+	return response, nil
 }
 
 func (s *server) GetClusterInfo(ctx context.Context, req *topology.GetClusterInfoRequest) (
 	*topology.GetClusterInfoResponse, error) {
-	return nil, nil
+	return &topology.GetClusterInfoResponse{
+		StatusCode: &status_success,
+		ClusterId:  &cluster_id,
+	}, nil
 }
 
 func (s *server) GetCopySetsInChunkServer(ctx context.Context, req *topology.GetCopySetsInChunkServerRequest) (
@@ -128,7 +223,16 @@ func (s *server) GetCopySetsInCluster(ctx context.Context, req *topology.GetCopy
 
 func (s *server) GetCopyset(ctx context.Context, req *topology.GetCopysetRequest) (
 	*topology.GetCopysetResponse, error) {
-	return nil, nil
+	if req.GetLogicalPoolId() != logical_pool_id {
+		return &topology.GetCopysetResponse{StatusCode: &status_copyset_not_found}, nil
+	}
+	return &topology.GetCopysetResponse{
+		StatusCode: &status_success,
+		CopysetInfo: &pbcommon.CopysetInfo{
+			LogicalPoolId: req.LogicalPoolId,
+			CopysetId:     req.CopysetId,
+		},
+	}, nil
 }
 
 func (s *server) GetLogicalPool(ctx context.Context, req *topology.GetLogicalPoolRequest) (
@@ -138,22 +242,63 @@ func (s *server) GetLogicalPool(ctx context.Context, req *topology.GetLogicalPoo
 
 func (s *server) GetPhysicalPool(ctx context.Context, req *topology.PhysicalPoolRequest) (
 	*topology.PhysicalPoolResponse, error) {
-	return nil, nil
+	if req.GetPhysicalPoolID() != physical_pool_id {
+		return &topology.PhysicalPoolResponse{StatusCode: &status_pool_not_found}, nil
+	}
+	return &topology.PhysicalPoolResponse{
+		StatusCode: &status_success,
+		PhysicalPoolInfo: &topology.PhysicalPoolInfo{
+			PhysicalPoolID:   &physical_pool_id,
+			PhysicalPoolName: &physical_pool_name,
+		},
+	}, nil
 }
 
 func (s *server) GetServer(ctx context.Context, req *topology.GetServerRequest) (
 	*topology.GetServerResponse, error) {
-	return nil, nil
+	if req.GetServerID() != server_id {
+		return &topology.GetServerResponse{StatusCode: &status_server_not_found}, nil
+	}
+	return &topology.GetServerResponse{
+		StatusCode: &status_success,
+		ServerInfo: &topology.ServerInfo{
+			ServerID:         &server_id,
+			HostName:         &server_host_name,
+			InternalIp:       &server_ip,
+			InternalPort:     &server_port,
+			ExternalIp:       &server_ip,
+			ExternalPort:     &server_port,
+			ZoneID:           &zone_id,
+			ZoneName:         &zone_name,
+			PhysicalPoolID:   &physical_pool_id,
+			PhysicalPoolName: &physical_pool_name,
+		},
+	}, nil
 }
 
 func (s *server) GetZone(ctx context.Context, req *topology.ZoneRequest) (
 	*topology.ZoneResponse, error) {
-	return nil, nil
+	if req.GetZoneID() != zone_id {
+		return &topology.ZoneResponse{StatusCode: &status_zone_not_found}, nil
+	}
+	return &topology.ZoneResponse{
+		StatusCode: &status_success,
+		ZoneInfo: &topology.ZoneInfo{
+			ZoneID:           &zone_id,
+			ZoneName:         &zone_name,
+			PhysicalPoolID:   &physical_pool_id,
+			PhysicalPoolName: &physical_pool_name,
+		},
+	}, nil
 }
 
 func (s *server) ListChunkServer(ctx context.Context, req *topology.ListChunkServerRequest) (
 	*topology.ListChunkServerResponse, error) {
-	return nil, nil
+	return &topology.ListChunkServerResponse{
+		StatusCode: &status_success,
+		ChunkServerInfos: []*topology.ChunkServerInfo{newChunkServerInfo(chunkserver_id, readwrite),
+			newChunkServerInfo(chunkserver_id+1, retired)},
+	}, nil
 }
 
 func (s *server) ListLogicalPool(ctx context.Context, req *topology.ListLogicalPoolRequest) (
@@ -168,7 +313,13 @@ func (s *server) ListPoolZone(ctx context.Context, req *topology.ListPoolZoneReq
 
 func (s *server) ListUnAvailCopySets(ctx context.Context, req *topology.ListUnAvailCopySetsRequest) (
 	*topology.ListUnAvailCopySetsResponse, error) {
-	return nil, nil
+	return &topology.ListUnAvailCopySetsResponse{
+		StatusCode: &status_success,
+		Copysets: []*pbcommon.CopysetInfo{{
+			LogicalPoolId: &logical_pool_id,
+			CopysetId:     &unavail_copyset_id,
+		}},
+	}, nil
 }
 
 func (s *server) ListZoneServer(ctx context.Context, req *topology.ListZoneServerRequest) (
@@ -178,27 +329,45 @@ func (s *server) ListZoneServer(ctx context.Context, req *topology.ListZoneServe
 
 func (s *server) RegistChunkServer(ctx context.Context, req *topology.ChunkServerRegistRequest) (
 	*topology.ChunkServerRegistResponse, error) {
-	return nil, nil
+	return &topology.ChunkServerRegistResponse{
+		StatusCode:    &status_success,
+		ChunkServerID: &chunkserver_id,
+	}, nil
 }
 
 func (s *server) RegistServer(ctx context.Context, req *topology.ServerRegistRequest) (
 	*topology.ServerRegistResponse, error) {
-	return nil, nil
+	if req.GetZoneName() != zone_name {
+		return &topology.ServerRegistResponse{StatusCode: &status_zone_not_found}, nil
+	}
+	return &topology.ServerRegistResponse{
+		StatusCode: &status_success,
+		ServerID:   &server_id,
+	}, nil
 }
 
 func (s *server) SetChunkServer(ctx context.Context, req *topology.SetChunkServerStatusRequest) (
 	*topology.SetChunkServerStatusResponse, error) {
-	return nil, nil
+	if req.GetChunkServerID() != chunkserver_id {
+		return &topology.SetChunkServerStatusResponse{StatusCode: &status_chunkserver_not_found}, nil
+	}
+	return &topology.SetChunkServerStatusResponse{StatusCode: &status_success}, nil
 }
 
 func (s *server) SetCopysetsAvailFlag(ctx context.Context, req *topology.SetCopysetsAvailFlagRequest) (
 	*topology.SetCopysetsAvailFlagResponse, error) {
-	return nil, nil
+	if len(req.GetCopysets()) == 0 {
+		return &topology.SetCopysetsAvailFlagResponse{StatusCode: &status_invalid_param}, nil
+	}
+	return &topology.SetCopysetsAvailFlagResponse{StatusCode: &status_success}, nil
 }
 
 func (s *server) SetLogicalPool(ctx context.Context, req *topology.SetLogicalPoolRequest) (
 	*topology.SetLogicalPoolResponse, error) {
-	return nil, nil
+	if req.GetLogicalPoolID() != logical_pool_id {
+		return &topology.SetLogicalPoolResponse{StatusCode: &status_logical_pool_not_found}, nil
+	}
+	return &topology.SetLogicalPoolResponse{StatusCode: &status_success}, nil
 }
 
 func (s *server) SetLogicalPoolScanState(ctx context.Context, req *topology.SetLogicalPoolScanStateRequest) (
@@ -218,16 +387,32 @@ func (s *server) ListPhysicalPool(ctx context.Context, req *topology.ListPhysica
 	}, nil
 }
 
+func newChunkServerInfo(id uint32, status topology.ChunkServerStatus) *topology.ChunkServerInfo {
+	var capacity uint64 = 100 * common.GiB
+	return &topology.ChunkServerInfo{
+		ChunkServerID: &id,
+		DiskType:      &disk_type,
+		HostIp:        &server_ip,
+		Port:          &chunkserver_port,
+		Status:        &status,
+		DiskStatus:    new(topology.DiskState),
+		OnlineState:   new(topology.OnlineState),
+		MountPoint:    &mount_point,
+		DiskCapacity:  &capacity,
+		DiskUsed:      new(uint64),
+	}
+}
+
 func init() {
+	gs = grpc.NewServer()
+	topology.RegisterTopologyServiceServer(gs, &server{})
+	// Register reflection service on gRPC server.
+	reflection.Register(gs)
 	go func() {
 		lis, err := net.Listen("tcp", port)
 		if err != nil {
 			fmt.Printf("failed to listen: %v", err)
 		}
-		gs = grpc.NewServer()
-		topology.RegisterTopologyServiceServer(gs, &server{})
-		// Register reflection service on gRPC server.
-		reflection.Register(gs)
 		if err := gs.Serve(lis); err != nil {
 			fmt.Printf("failed to serve: %v", err)
 		}
@@ -236,10 +421,18 @@ func init() {
 
 func teardown() {
 	gs.Stop()
+	fsGs.Stop()
+	csGs.Stop()
+	leaderGs.Stop()
+	noLeaderGs.Stop()
+	copysetGs.Stop()
+	followerGs.Stop()
+	chunkLeaderGs.Stop()
 }
 
 func TestListPhysicalPool(t *testing.T) {
 	mdsClient := NewMdsClient(clientOption)
+	defer mdsClient.Close()
 	pools, err := mdsClient.ListPhysicalPool()
 	if err != nil {
 		t.Errorf("TestListPhysicalPool rpc failed, error = %v", err)
@@ -253,6 +446,286 @@ func TestListPhysicalPool(t *testing.T) {
 	}
 }
 
+func TestPhysicalPoolAdmin(t *testing.T) {
+	mdsClient := NewMdsClient(clientOption)
+	defer mdsClient.Close()
+	pool, err := mdsClient.CreatePhysicalPool(physical_pool_name, "desc")
+	if err != nil {
+		t.Fatalf("TestPhysicalPoolAdmin create rpc failed, error = %v", err)
+	}
+	if pool.Id != physical_pool_id || pool.Name != physical_pool_name || pool.Desc != "desc" {
+		t.Errorf("TestPhysicalPoolAdmin create response failed, actual pool = %+v", pool)
+	}
+
+	pool, err = mdsClient.GetPhysicalPool(physical_pool_id)
+	if err != nil {
+		t.Fatalf("TestPhysicalPoolAdmin get rpc failed, error = %v", err)
+	}
+	if pool.Id != physical_pool_id || pool.Name != physical_pool_name {
+		t.Errorf("TestPhysicalPoolAdmin get response failed, actual pool = %+v", pool)
+	}
+
+	if err := mdsClient.DeletePhysicalPool(physical_pool_id); err != nil {
+		t.Errorf("TestPhysicalPoolAdmin delete rpc failed, error = %v", err)
+	}
+	if err := mdsClient.DeletePhysicalPool(physical_pool_id + 1); !errors.Is(err, rpcerr.ErrPhysicalPoolNotFound) {
+		t.Errorf("TestPhysicalPoolAdmin expected ErrPhysicalPoolNotFound, actual error = %v", err)
+	}
+}
+
+func TestZoneAdmin(t *testing.T) {
+	mdsClient := NewMdsClient(clientOption)
+	defer mdsClient.Close()
+	zone, err := mdsClient.CreateZone(zone_name, physical_pool_name, "")
+	if err != nil {
+		t.Fatalf("TestZoneAdmin create rpc failed, error = %v", err)
+	}
+	if zone.Id != zone_id || zone.Name != zone_name || zone.PhysicalPoolId != physical_pool_id {
+		t.Errorf("TestZoneAdmin create response failed, actual zone = %+v", zone)
+	}
+	if _, err := mdsClient.CreateZone(zone_name, "unknown", ""); !errors.Is(err, rpcerr.ErrPhysicalPoolNotFound) {
+		t.Errorf("TestZoneAdmin expected ErrPhysicalPoolNotFound, actual error = %v", err)
+	}
+
+	zone, err = mdsClient.GetZone(zone_id)
+	if err != nil {
+		t.Fatalf("TestZoneAdmin get rpc failed, error = %v", err)
+	}
+	if zone.Id != zone_id || zone.PhysicalPoolName != physical_pool_name {
+		t.Errorf("TestZoneAdmin get response failed, actual zone = %+v", zone)
+	}
+
+	if err := mdsClient.DeleteZone(zone_id); err != nil {
+		t.Errorf("TestZoneAdmin delete rpc failed, error = %v", err)
+	}
+	if _, err := mdsClient.GetZone(zone_id + 1); !errors.Is(err, rpcerr.ErrZoneNotFound) {
+		t.Errorf("TestZoneAdmin expected ErrZoneNotFound, actual error = %v", err)
+	}
+}
+
+func TestServerAdmin(t *testing.T) {
+	mdsClient := NewMdsClient(clientOption)
+	defer mdsClient.Close()
+	id, err := mdsClient.RegistServer(Server{
+		HostName:         server_host_name,
+		InternalIp:       server_ip,
+		InternalPort:     server_port,
+		ExternalIp:       server_ip,
+		ExternalPort:     server_port,
+		ZoneName:         zone_name,
+		PhysicalPoolName: physical_pool_name,
+	})
+	if err != nil {
+		t.Fatalf("TestServerAdmin regist rpc failed, error = %v", err)
+	}
+	if id != server_id {
+		t.Errorf("TestServerAdmin regist response failed, expected id = %d, actual id = %d", server_id, id)
+	}
+
+	server, err := mdsClient.GetServer(server_id)
+	if err != nil {
+		t.Fatalf("TestServerAdmin get rpc failed, error = %v", err)
+	}
+	if server.Id != server_id || server.HostName != server_host_name || server.InternalPort != server_port ||
+		server.ZoneId != zone_id || server.PhysicalPoolId != physical_pool_id {
+		t.Errorf("TestServerAdmin get response failed, actual server = %+v", server)
+	}
+
+	if err := mdsClient.DeleteServer(server_id); err != nil {
+		t.Errorf("TestServerAdmin delete rpc failed, error = %v", err)
+	}
+	if err := mdsClient.DeleteServer(server_id + 1); !errors.Is(err, rpcerr.ErrServerNotFound) {
+		t.Errorf("TestServerAdmin expected ErrServerNotFound, actual error = %v", err)
+	}
+}
+
+func TestLogicalPoolAdmin(t *testing.T) {
+	mdsClient := NewMdsClient(clientOption)
+	defer mdsClient.Close()
+	option := LogicalPoolOption{
+		Name:             logical_pool_name,
+		PhysicalPoolName: physical_pool_name,
+		Type:             PAGEFILE_TYPE,
+		ReplicaNum:       3,
+		CopysetNum:       100,
+		ZoneNum:          3,
+	}
+	pool, err := mdsClient.CreateLogicalPool(option)
+	if err != nil {
+		t.Fatalf("TestLogicalPoolAdmin create rpc failed, error = %v", err)
+	}
+	if pool.Id != logical_pool_id || pool.Name != logical_pool_name || pool.Type != PAGEFILE_TYPE ||
+		pool.AllocateStatus != ALLOW_STATUS {
+		t.Errorf("TestLogicalPoolAdmin create response failed, actual pool = %+v", pool)
+	}
+
+	option.Type = "UNKNOWN"
+	if _, err := mdsClient.CreateLogicalPool(option); !errors.Is(err, rpcerr.ErrInvalidParam) {
+		t.Errorf("TestLogicalPoolAdmin expected ErrInvalidParam, actual error = %v", err)
+	}
+
+	if err := mdsClient.SetLogicalPool(logical_pool_id, DENY_STATUS); err != nil {
+		t.Errorf("TestLogicalPoolAdmin set rpc failed, error = %v", err)
+	}
+	if err := mdsClient.SetLogicalPool(logical_pool_id+1, ALLOW_STATUS); !errors.Is(err, rpcerr.ErrLogicalPoolNotFound) {
+		t.Errorf("TestLogicalPoolAdmin expected ErrLogicalPoolNotFound, actual error = %v", err)
+	}
+	if err := mdsClient.SetLogicalPool(logical_pool_id, INVALID); !errors.Is(err, rpcerr.ErrInvalidParam) {
+		t.Errorf("TestLogicalPoolAdmin expected ErrInvalidParam, actual error = %v", err)
+	}
+}
+
+func TestListChunkServer(t *testing.T) {
+	mdsClient := NewMdsClient(clientOption)
+	defer mdsClient.Close()
+	css, err := mdsClient.ListChunkServer(server_id)
+	if err != nil {
+		t.Fatalf("TestListChunkServer rpc failed, error = %v", err)
+	}
+	if len(css) != 1 || css[0].Id != chunkserver_id {
+		t.Errorf("TestListChunkServer expected retired chunkserver skipped, actual chunkservers = %+v", css)
+	}
+
+	css, err = mdsClient.ListChunkServer(server_id, ListChunkServerOption{IncludeRetired: true})
+	if err != nil {
+		t.Fatalf("TestListChunkServer rpc failed, error = %v", err)
+	}
+	if len(css) != 2 || css[1].Status != RETIRED_STATUS {
+		t.Errorf("TestListChunkServer expected retired chunkserver included, actual chunkservers = %+v", css)
+	}
+}
+
+func TestChunkServerAdmin(t *testing.T) {
+	mdsClient := NewMdsClient(clientOption)
+	defer mdsClient.Close()
+	id, err := mdsClient.RegistChunkServer(ChunkServer{
+		DiskType:   disk_type,
+		HostIp:     server_ip,
+		Port:       chunkserver_port,
+		MountPoint: mount_point,
+	})
+	if err != nil {
+		t.Fatalf("TestChunkServerAdmin regist rpc failed, error = %v", err)
+	}
+	if id != chunkserver_id {
+		t.Errorf("TestChunkServerAdmin regist response failed, expected id = %d, actual id = %d", chunkserver_id, id)
+	}
+
+	cs, err := mdsClient.GetChunkServerByAddr(server_ip, chunkserver_port)
+	if err != nil {
+		t.Fatalf("TestChunkServerAdmin get rpc failed, error = %v", err)
+	}
+	if cs.Id != chunkserver_id || cs.MountPoint != mount_point || cs.DiskCapacity != "100" ||
+		cs.DiskCapacityBytes != 100*common.GiB {
+		t.Errorf("TestChunkServerAdmin get response failed, actual chunkserver = %+v", cs)
+	}
+	if _, err := mdsClient.GetChunkServer(chunkserver_id + 1); !errors.Is(err, rpcerr.ErrChunkServerNotFound) {
+		t.Errorf("TestChunkServerAdmin expected ErrChunkServerNotFound, actual error = %v", err)
+	}
+
+	if err := mdsClient.SetChunkServer(chunkserver_id, PENDDING_STATUS); err != nil {
+		t.Errorf("TestChunkServerAdmin set rpc failed, error = %v", err)
+	}
+	if err := mdsClient.SetChunkServer(chunkserver_id, ONLINE_STATUS); !errors.Is(err, rpcerr.ErrInvalidParam) {
+		t.Errorf("TestChunkServerAdmin expected ErrInvalidParam, actual error = %v", err)
+	}
+}
+
+func TestUnAvailCopySets(t *testing.T) {
+	mdsClient := NewMdsClient(clientOption)
+	defer mdsClient.Close()
+	copysets, err := mdsClient.ListUnAvailCopySets()
+	if err != nil {
+		t.Fatalf("TestUnAvailCopySets list rpc failed, error = %v", err)
+	}
+	if len(copysets) != 1 || copysets[0].CopysetId != unavail_copyset_id || copysets[0].Available ||
+		len(copysets[0].Peers) != 1 || copysets[0].Peers[0].ChunkServerId != chunkserver_id {
+		t.Errorf("TestUnAvailCopySets list response failed, actual copysets = %+v", copysets)
+	}
+
+	copyset, err := mdsClient.GetCopyset(logical_pool_id, copyset_id)
+	if err != nil {
+		t.Fatalf("TestUnAvailCopySets get rpc failed, error = %v", err)
+	}
+	if copyset.CopysetId != copyset_id || !copyset.Available || len(copyset.Peers) != 1 {
+		t.Errorf("TestUnAvailCopySets get response failed, actual copyset = %+v", copyset)
+	}
+	copyset, err = mdsClient.GetCopyset(logical_pool_id, unavail_copyset_id)
+	if err != nil {
+		t.Fatalf("TestUnAvailCopySets get rpc failed, error = %v", err)
+	}
+	if copyset.Available {
+		t.Errorf("TestUnAvailCopySets expected copyset %d unavailable", unavail_copyset_id)
+	}
+	if _, err := mdsClient.GetCopyset(logical_pool_id+1, copyset_id); !errors.Is(err, rpcerr.ErrCopySetNotFound) {
+		t.Errorf("TestUnAvailCopySets expected ErrCopySetNotFound, actual error = %v", err)
+	}
+
+	if err := mdsClient.SetCopysetsAvailFlag([]CopySetInfo{copysets[0].CopySetInfo}, true); err != nil {
+		t.Errorf("TestUnAvailCopySets set rpc failed, error = %v", err)
+	}
+}
+
+func TestExpectedClusterId(t *testing.T) {
+	option := clientOption
+	option.ExpectedClusterId = cluster_id
+	mdsClient := NewMdsClient(option)
+	defer mdsClient.Close()
+	info, err := mdsClient.GetClusterInfo()
+	if err != nil {
+		t.Fatalf("TestExpectedClusterId rpc failed, error = %v", err)
+	}
+	if info.ClusterId != cluster_id {
+		t.Errorf("TestExpectedClusterId expected cluster id = %s, actual cluster id = %s", cluster_id, info.ClusterId)
+	}
+	if err := mdsClient.DeletePhysicalPool(physical_pool_id); err != nil {
+		t.Errorf("TestExpectedClusterId delete rpc failed, error = %v", err)
+	}
+
+	option.ExpectedClusterId = "another-cluster"
+	otherClient := NewMdsClient(option)
+	defer otherClient.Close()
+	if err := otherClient.DeletePhysicalPool(physical_pool_id); !errors.Is(err, rpcerr.ErrClusterMismatch) {
+		t.Errorf("TestExpectedClusterId expected ErrClusterMismatch, actual error = %v", err)
+	}
+	if _, err := otherClient.ListPhysicalPool(); err != nil {
+		t.Errorf("TestExpectedClusterId expected read rpc allowed, actual error = %v", err)
+	}
+}
+
+func TestSharedConnPool(t *testing.T) {
+	first := NewMdsClient(clientOption)
+	defer first.Close()
+	second := NewMdsClient(clientOption)
+	defer second.Close()
+	csClient := NewChunkserverClient(csClientOption)
+	defer csClient.Close()
+	if first.ownPool || first.baseClient.Pool != baserpc.DefaultConnPool() ||
+		csClient.ownPool || csClient.baseClient.Pool != baserpc.DefaultConnPool() {
+		t.Fatalf("TestSharedConnPool expected clients without pool to share the default pool")
+	}
+	addr := clientOption.Addrs[0]
+	firstConn, err := first.baseClient.Pool.Get(context.Background(), addr)
+	if err != nil {
+		t.Fatalf("TestSharedConnPool get conn failed, error = %v", err)
+	}
+	secondConn, err := second.baseClient.Pool.Get(context.Background(), addr)
+	if err != nil {
+		t.Fatalf("TestSharedConnPool get conn failed, error = %v", err)
+	}
+	if firstConn != secondConn {
+		t.Errorf("TestSharedConnPool expected the same conn of %s", addr)
+	}
+
+	option := clientOption
+	option.ConnIdleTimeoutMs = 1000
+	private := NewMdsClient(option)
+	defer private.Close()
+	if !private.ownPool || private.baseClient.Pool == baserpc.DefaultConnPool() {
+		t.Errorf("TestSharedConnPool expected own pool with ConnIdleTimeoutMs")
+	}
+}
+
 func TestMain(m *testing.M) {
 	code := m.Run()
 	teardown()
@@ -0,0 +1,154 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"context"
+	"path"
+	"sync"
+)
+
+const (
+	DEFAULT_WALK_CONCURRENCY = 8
+)
+
+// WalkFunc is called for each file found by Walk with its full path.
+// If listing directory path failed, it is called with the error and the
+// info of directory, and the walk goes on with other directories.
+// Walk stops and returns the error if WalkFunc returns a non-nil error.
+// Calls are serialized, so WalkFunc needs no locking.
+type WalkFunc func(path string, info FileInfo, err error) error
+
+type WalkOption struct {
+	// sign each ListDir, as signatures expire during long walks
+	Credential Credential
+	// the number of ListDir in flight, DEFAULT_WALK_CONCURRENCY if not set
+	Concurrency int
+	// files in root are at depth 1, unlimited if not set
+	MaxDepth int
+	// only files matching any of Include are passed to WalkFunc if set,
+	// directories are walked whether they are matched or not
+	Include []string
+	// files matching any of Exclude are skipped, and so are files in them
+	Exclude []string
+}
+
+type walker struct {
+	cli    *MdsClient
+	ctx    context.Context
+	cancel context.CancelFunc
+	option WalkOption
+	fn     WalkFunc
+	sem    chan struct{}
+	wg     sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+}
+
+// walk the namespace tree under root in parallel, patterns of filters are
+// matched against full paths with path.Match.
+// The length of files is in GiB as that of ListDir, see also FileInfo.LengthBytes.
+func (cli *MdsClient) Walk(root string, option WalkOption, fn WalkFunc) error {
+	return cli.WalkWithContext(context.Background(), root, option, fn)
+}
+
+func (cli *MdsClient) WalkWithContext(ctx context.Context, root string, option WalkOption, fn WalkFunc) error {
+	if option.Concurrency <= 0 {
+		option.Concurrency = DEFAULT_WALK_CONCURRENCY
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := &walker{
+		cli:    cli,
+		ctx:    ctx,
+		cancel: cancel,
+		option: option,
+		fn:     fn,
+		sem:    make(chan struct{}, option.Concurrency),
+	}
+	w.wg.Add(1)
+	go w.walkDir(path.Clean(root), FileInfo{FileName: root, FileType: INODE_DIRECTORY}, 1)
+	w.wg.Wait()
+
+	if w.err != nil {
+		return w.err
+	}
+	return ctx.Err()
+}
+
+func match(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// call fn unless the walk is stopped, return false if it stops
+func (w *walker) call(path string, info FileInfo, err error) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil || w.ctx.Err() != nil {
+		return false
+	}
+	if ret := w.fn(path, info, err); ret != nil {
+		w.err = ret
+		w.cancel()
+		return false
+	}
+	return true
+}
+
+func (w *walker) walkDir(dir string, info FileInfo, depth int) {
+	defer w.wg.Done()
+	select {
+	case w.sem <- struct{}{}:
+	case <-w.ctx.Done():
+		return
+	}
+	sig, date := w.option.Credential.Sign()
+	files, err := w.cli.ListDirWithContext(w.ctx, dir, w.option.Credential.Owner, sig, date)
+	<-w.sem
+	if err != nil {
+		w.call(dir, info, err)
+		return
+	}
+
+	for _, file := range files {
+		name := path.Join(dir, path.Base(file.FileName))
+		if match(w.option.Exclude, name) {
+			continue
+		}
+		if len(w.option.Include) == 0 || match(w.option.Include, name) {
+			if !w.call(name, file, nil) {
+				return
+			}
+		}
+		if file.FileType == INODE_DIRECTORY && (w.option.MaxDepth <= 0 || depth < w.option.MaxDepth) {
+			w.wg.Add(1)
+			go w.walkDir(name, file, depth+1)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package curvebs
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/SeanHai/curve-go-rpc/rpc/rpcerr"
+)
+
+func TestWalk(t *testing.T) {
+	mdsClient := NewMdsClient(fsClientOption)
+	defer mdsClient.Close()
+
+	var paths []string
+	var failed []string
+	walk := func(path string, info FileInfo, err error) error {
+		if err != nil {
+			if !errors.Is(err, rpcerr.ErrOwnerAuthFail) {
+				t.Errorf("TestWalk expected ErrOwnerAuthFail of %s, actual error = %v", path, err)
+			}
+			failed = append(failed, path)
+			return nil
+		}
+		paths = append(paths, path)
+		return nil
+	}
+	option := WalkOption{
+		Credential:  Credential{Owner: file_owner},
+		Concurrency: 2,
+		Exclude:     []string{"/dir1/skip"},
+	}
+	if err := mdsClient.Walk("/", option, walk); err != nil {
+		t.Fatalf("TestWalk walk failed, error = %v", err)
+	}
+	sort.Strings(paths)
+	expected := []string{"/dir1", "/dir1/dir2", "/dir1/vol2", "/vol1"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("TestWalk expected paths = %v, actual paths = %v", expected, paths)
+	}
+	if !reflect.DeepEqual(failed, []string{"/dir1/dir2"}) {
+		t.Errorf("TestWalk expected failed dirs = [/dir1/dir2], actual = %v", failed)
+	}
+
+	// only volumes in root
+	paths, failed = nil, nil
+	option.MaxDepth = 1
+	option.Include = []string{"/vol*", "/dir1/vol*"}
+	if err := mdsClient.Walk("/", option, walk); err != nil {
+		t.Fatalf("TestWalk walk with depth failed, error = %v", err)
+	}
+	if !reflect.DeepEqual(paths, []string{"/vol1"}) || len(failed) != 0 {
+		t.Errorf("TestWalk with depth expected paths = [/vol1], actual paths = %v, failed = %v", paths, failed)
+	}
+
+	// stop at the first file
+	stop := errors.New("stop")
+	count := 0
+	err := mdsClient.Walk("/", WalkOption{}, func(path string, info FileInfo, err error) error {
+		count++
+		return stop
+	})
+	if !errors.Is(err, stop) || count != 1 {
+		t.Errorf("TestWalk expected stop after 1 call, actual calls = %d, error = %v", count, err)
+	}
+}
@@ -0,0 +1,258 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package rpcerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/chunk"
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/copyset"
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/nameserver2"
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/topology/statuscode"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	NAMESERVER_SERVICE = "nameserver2"
+	TOPOLOGY_SERVICE   = "topology"
+	COPYSET_SERVICE    = "copyset"
+	CHUNK_SERVICE      = "chunk"
+)
+
+// sentinels to check with errors.Is
+var (
+	// transport
+	ErrTimeout  = errors.New("timeout")
+	ErrCanceled = errors.New("canceled")
+	ErrNoLeader = errors.New("no leader")
+
+	// client side checks
+	ErrClusterMismatch = errors.New("cluster id mismatch")
+	ErrLeaseExpired    = errors.New("lease expired")
+
+	// status codes
+	ErrInvalidParam         = errors.New("invalid param")
+	ErrStorage              = errors.New("storage error")
+	ErrFileExists           = errors.New("file exists")
+	ErrFileNotExists        = errors.New("file not exists")
+	ErrNotDirectory         = errors.New("not directory")
+	ErrOwnerAuthFail        = errors.New("owner auth fail")
+	ErrDirNotEmpty          = errors.New("dir not empty")
+	ErrFileUnderSnapShot    = errors.New("file under snapshot")
+	ErrFileNotUnderSnapShot = errors.New("file not under snapshot")
+	ErrSnapshotNotExists    = errors.New("snapshot file not exists")
+	ErrSnapshotDeleting     = errors.New("snapshot deleting")
+	ErrSessionNotExist      = errors.New("session not exist")
+	ErrFileOccupied         = errors.New("file occupied")
+	ErrFileIdNotMatch       = errors.New("file id not match")
+	ErrFileUnderDeleting    = errors.New("file under deleting")
+	ErrCloneStatusNotMatch  = errors.New("clone status not match")
+	ErrSegmentNotAllocated  = errors.New("segment not allocated")
+	ErrChunkServerNotFound  = errors.New("chunkserver not found")
+	ErrServerNotFound       = errors.New("server not found")
+	ErrZoneNotFound         = errors.New("zone not found")
+	ErrPhysicalPoolNotFound = errors.New("physical pool not found")
+	ErrLogicalPoolNotFound  = errors.New("logical pool not found")
+	ErrCopySetNotFound      = errors.New("copyset not found")
+	ErrNameDuplicated       = errors.New("name duplicated")
+	ErrCopySetExists        = errors.New("copyset exists")
+	ErrRedirected           = errors.New("redirected")
+	ErrCrcFail              = errors.New("crc fail")
+	ErrNoSpace              = errors.New("no space")
+	ErrChunkNotExists       = errors.New("chunk not exists")
+	ErrChunkExists          = errors.New("chunk exists")
+	ErrOverload             = errors.New("overload")
+	ErrEpochTooOld          = errors.New("epoch too old")
+)
+
+var nameServerErrs = map[nameserver2.StatusCode]error{
+	nameserver2.StatusCode_kParaError:             ErrInvalidParam,
+	nameserver2.StatusCode_kStorageError:          ErrStorage,
+	nameserver2.StatusCode_kFileExists:            ErrFileExists,
+	nameserver2.StatusCode_kFileNotExists:         ErrFileNotExists,
+	nameserver2.StatusCode_kNotDirectory:          ErrNotDirectory,
+	nameserver2.StatusCode_kOwnerAuthFail:         ErrOwnerAuthFail,
+	nameserver2.StatusCode_kDirNotEmpty:           ErrDirNotEmpty,
+	nameserver2.StatusCode_kFileUnderSnapShot:     ErrFileUnderSnapShot,
+	nameserver2.StatusCode_kFileNotUnderSnapShot:  ErrFileNotUnderSnapShot,
+	nameserver2.StatusCode_kSnapshotFileNotExists: ErrSnapshotNotExists,
+	nameserver2.StatusCode_kSnapshotDeleting:      ErrSnapshotDeleting,
+	nameserver2.StatusCode_kSessionNotExist:       ErrSessionNotExist,
+	nameserver2.StatusCode_kFileOccupied:          ErrFileOccupied,
+	nameserver2.StatusCode_kFileIdNotMatch:        ErrFileIdNotMatch,
+	nameserver2.StatusCode_kFileUnderDeleting:     ErrFileUnderDeleting,
+	nameserver2.StatusCode_kCloneStatusNotMatch:   ErrCloneStatusNotMatch,
+	nameserver2.StatusCode_kSegmentNotAllocated:   ErrSegmentNotAllocated,
+}
+
+var topologyErrs = map[statuscode.TopoStatusCode]error{
+	statuscode.TopoStatusCode_InvalidParam:         ErrInvalidParam,
+	statuscode.TopoStatusCode_StorgeFail:           ErrStorage,
+	statuscode.TopoStatusCode_ChunkServerNotFound:  ErrChunkServerNotFound,
+	statuscode.TopoStatusCode_ServerNotFound:       ErrServerNotFound,
+	statuscode.TopoStatusCode_ZoneNotFound:         ErrZoneNotFound,
+	statuscode.TopoStatusCode_PhysicalPoolNotFound: ErrPhysicalPoolNotFound,
+	statuscode.TopoStatusCode_LogicalPoolNotFound:  ErrLogicalPoolNotFound,
+	statuscode.TopoStatusCode_CopySetNotFound:      ErrCopySetNotFound,
+	statuscode.TopoStatusCode_NameDuplicated:       ErrNameDuplicated,
+}
+
+var copysetErrs = map[copyset.COPYSET_OP_STATUS]error{
+	copyset.COPYSET_OP_STATUS_COPYSET_OP_STATUS_EXIST:            ErrCopySetExists,
+	copyset.COPYSET_OP_STATUS_COPYSET_OP_STATUS_COPYSET_NOTEXIST: ErrCopySetNotFound,
+}
+
+var chunkErrs = map[chunk.CHUNK_OP_STATUS]error{
+	chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_REDIRECTED:       ErrRedirected,
+	chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_DISK_FAIL:        ErrStorage,
+	chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_CRC_FAIL:         ErrCrcFail,
+	chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_INVALID_REQUEST:  ErrInvalidParam,
+	chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_NOSPACE:          ErrNoSpace,
+	chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_COPYSET_NOTEXIST: ErrCopySetNotFound,
+	chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_CHUNK_NOTEXIST:   ErrChunkNotExists,
+	chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_OVERLOAD:         ErrOverload,
+	chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_CHUNK_EXIST:      ErrChunkExists,
+	chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_EPOCH_TOO_OLD:    ErrEpochTooOld,
+}
+
+// StatusError means the server handled the rpc but answered a failure status code.
+type StatusError struct {
+	Rpc     string
+	Service string
+	Code    int32
+	Name    string
+	err     error
+}
+
+func NewNameServerError(rpc string, code nameserver2.StatusCode) error {
+	return &StatusError{
+		Rpc:     rpc,
+		Service: NAMESERVER_SERVICE,
+		Code:    int32(code),
+		Name:    nameserver2.StatusCode_name[int32(code)],
+		err:     nameServerErrs[code],
+	}
+}
+
+func NewTopologyError(rpc string, code int32) error {
+	return &StatusError{
+		Rpc:     rpc,
+		Service: TOPOLOGY_SERVICE,
+		Code:    code,
+		Name:    statuscode.TopoStatusCode_name[code],
+		err:     topologyErrs[statuscode.TopoStatusCode(code)],
+	}
+}
+
+func NewCopysetError(rpc string, code copyset.COPYSET_OP_STATUS) error {
+	return &StatusError{
+		Rpc:     rpc,
+		Service: COPYSET_SERVICE,
+		Code:    int32(code),
+		Name:    copyset.COPYSET_OP_STATUS_name[int32(code)],
+		err:     copysetErrs[code],
+	}
+}
+
+func NewChunkError(rpc string, code chunk.CHUNK_OP_STATUS) error {
+	return &StatusError{
+		Rpc:     rpc,
+		Service: CHUNK_SERVICE,
+		Code:    int32(code),
+		Name:    chunk.CHUNK_OP_STATUS_name[int32(code)],
+		err:     chunkErrs[code],
+	}
+}
+
+func (e *StatusError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("%s: unknown %s status code %d", e.Rpc, e.Service, e.Code)
+	}
+	return fmt.Sprintf("%s: %s", e.Rpc, e.Name)
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.err
+}
+
+type AddrError struct {
+	Addr string
+	Err  error
+}
+
+// TransportError means the rpc is not handled by any addr, Errs keeps the
+// error of each addr tried.
+type TransportError struct {
+	Rpc  string
+	Errs []AddrError
+}
+
+func (e *TransportError) Error() string {
+	var errs []string
+	for _, err := range e.Errs {
+		if err.Addr == "" {
+			errs = append(errs, err.Err.Error())
+		} else {
+			errs = append(errs, fmt.Sprintf("%s: %v", err.Addr, err.Err))
+		}
+	}
+	return fmt.Sprintf("%s: %s", e.Rpc, strings.Join(errs, "; "))
+}
+
+// Is reports ErrTimeout/ErrCanceled if any addr failed so, and ErrNoLeader if
+// no addr was reachable.
+func (e *TransportError) Is(target error) bool {
+	switch target {
+	case ErrTimeout:
+		return e.any(isTimeout)
+	case ErrCanceled:
+		return e.any(isCanceled)
+	case ErrNoLeader:
+		return len(e.Errs) > 0 && !e.any(func(err error) bool {
+			return !isTimeout(err) && status.Code(err) != codes.Unavailable
+		})
+	default:
+		return false
+	}
+}
+
+func (e *TransportError) any(match func(err error) bool) bool {
+	for _, err := range e.Errs {
+		if match(err.Err) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded
+}
+
+func isCanceled(err error) bool {
+	return errors.Is(err, context.Canceled) || status.Code(err) == codes.Canceled
+}
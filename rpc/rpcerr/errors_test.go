@@ -0,0 +1,91 @@
+/*
+*  Copyright (c) 2023 NetEase Inc.
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+ */
+
+/*
+* Project: Curve-Go-RPC
+* Created Date: 2023-03-03
+* Author: wanghai (SeanHai)
+ */
+
+package rpcerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/chunk"
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/copyset"
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/nameserver2"
+	"github.com/SeanHai/curve-go-rpc/curvebs_proto/proto/topology/statuscode"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusError(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", NewNameServerError("DeleteFile", nameserver2.StatusCode_kFileNotExists))
+	if !errors.Is(err, ErrFileNotExists) {
+		t.Errorf("TestStatusError expected ErrFileNotExists, actual error = %v", err)
+	}
+	if errors.Is(err, ErrOwnerAuthFail) {
+		t.Errorf("TestStatusError unexpected ErrOwnerAuthFail, actual error = %v", err)
+	}
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Rpc != "DeleteFile" ||
+		statusErr.Code != int32(nameserver2.StatusCode_kFileNotExists) {
+		t.Errorf("TestStatusError expected StatusError of DeleteFile, actual error = %v", err)
+	}
+
+	err = NewTopologyError("GetLogicalPool", int32(statuscode.TopoStatusCode_LogicalPoolNotFound))
+	if !errors.Is(err, ErrLogicalPoolNotFound) {
+		t.Errorf("TestStatusError expected ErrLogicalPoolNotFound, actual error = %v", err)
+	}
+
+	err = NewCopysetError("GetCopysetStatus", copyset.COPYSET_OP_STATUS_COPYSET_OP_STATUS_COPYSET_NOTEXIST)
+	if !errors.Is(err, ErrCopySetNotFound) {
+		t.Errorf("TestStatusError expected ErrCopySetNotFound, actual error = %v", err)
+	}
+
+	err = NewChunkError("ReadChunk", chunk.CHUNK_OP_STATUS_CHUNK_OP_STATUS_REDIRECTED)
+	if !errors.Is(err, ErrRedirected) {
+		t.Errorf("TestStatusError expected ErrRedirected, actual error = %v", err)
+	}
+}
+
+func TestTransportError(t *testing.T) {
+	err := &TransportError{
+		Rpc: "ListPhysicalPool",
+		Errs: []AddrError{
+			{Addr: "127.0.0.1:6666", Err: context.DeadlineExceeded},
+			{Addr: "127.0.0.1:6667", Err: status.Error(codes.Unavailable, "connection refused")},
+		},
+	}
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("TestTransportError expected ErrTimeout, actual error = %v", err)
+	}
+	if !errors.Is(err, ErrNoLeader) {
+		t.Errorf("TestTransportError expected ErrNoLeader, actual error = %v", err)
+	}
+	if errors.Is(err, ErrCanceled) {
+		t.Errorf("TestTransportError unexpected ErrCanceled, actual error = %v", err)
+	}
+
+	err.Errs = append(err.Errs, AddrError{Addr: "127.0.0.1:6668", Err: status.Error(codes.Internal, "internal")})
+	if errors.Is(err, ErrNoLeader) {
+		t.Errorf("TestTransportError unexpected ErrNoLeader, actual error = %v", err)
+	}
+}